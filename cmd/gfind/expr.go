@@ -0,0 +1,506 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karrick/godirwalk/match"
+)
+
+// expr is an expression evaluated against each file system node.
+type expr interface {
+	eval(f *finder, n *node) bool
+}
+
+// primary is a test, an action, or an option of an expression.
+type primary func(f *finder, n *node) bool
+
+func (p primary) eval(f *finder, n *node) bool { return p(f, n) }
+
+type and struct{ left, right expr }
+
+func (e and) eval(f *finder, n *node) bool { return e.left.eval(f, n) && e.right.eval(f, n) }
+
+type or struct{ left, right expr }
+
+func (e or) eval(f *finder, n *node) bool { return e.left.eval(f, n) || e.right.eval(f, n) }
+
+type not struct{ e expr }
+
+func (e not) eval(f *finder, n *node) bool { return !e.e.eval(f, n) }
+
+type comma struct{ left, right expr }
+
+func (e comma) eval(f *finder, n *node) bool {
+	_ = e.left.eval(f, n)
+	return e.right.eval(f, n)
+}
+
+// always is the primary of options, such as -maxdepth, which are true.
+func always(*finder, *node) bool { return true }
+
+// parser parses the expression from the command line arguments.
+type parser struct {
+	f       *finder
+	args    []string
+	printed bool // whether the expression has an action other than -prune
+}
+
+// parse parses args as an expression, printing the nodes for which it is true
+// unless it has an action other than -prune.
+func (f *finder) parse(args []string) (expr, error) {
+	p := &parser{f: f, args: args}
+
+	var e expr = primary(always)
+	if len(args) > 0 {
+		var err error
+		if e, err = p.parseComma(); err != nil {
+			return nil, err
+		}
+		if len(p.args) > 0 {
+			return nil, fmt.Errorf("unexpected argument: %s", p.args[0])
+		}
+	}
+	if !p.printed {
+		e = and{e, primary(printPath)}
+	}
+	return e, nil
+}
+
+// peek returns the next argument, or the empty string when there are none.
+func (p *parser) peek() string {
+	if len(p.args) == 0 {
+		return ""
+	}
+	return p.args[0]
+}
+
+// next consumes and returns the next argument, or returns an error mentioning
+// the primary requiring it when there are none.
+func (p *parser) next(name string) (string, error) {
+	if len(p.args) == 0 {
+		return "", fmt.Errorf("missing argument to %s", name)
+	}
+	arg := p.args[0]
+	p.args = p.args[1:]
+	return arg, nil
+}
+
+func (p *parser) parseComma() (expr, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "," {
+		p.args = p.args[1:]
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = comma{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "-o" || p.peek() == "-or" {
+		p.args = p.args[1:]
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "", ")", ",", "-o", "-or":
+			return left, nil
+		case "-a", "-and":
+			p.args = p.args[1:]
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+}
+
+func (p *parser) parseNot() (expr, error) {
+	switch p.peek() {
+	case "!", "-not":
+		p.args = p.args[1:]
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return not{e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	arg, err := p.next("expression")
+	if err != nil {
+		return nil, fmt.Errorf("expected an expression")
+	}
+
+	switch arg {
+	case "(":
+		e, err := p.parseComma()
+		if err != nil {
+			return nil, err
+		}
+		if arg, _ = p.next(arg); arg != ")" {
+			return nil, fmt.Errorf("missing closing ')'")
+		}
+		return e, nil
+
+	case "-depth":
+		p.f.depthFirst = true
+		return primary(always), nil
+	case "-maxdepth":
+		arg, err := p.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid argument to -maxdepth: %s", arg)
+		}
+		p.f.maxDepth = n
+		return primary(always), nil
+	case "-xdev", "-mount":
+		p.f.xdev = true
+		return primary(always), nil
+
+	case "-true":
+		return primary(always), nil
+	case "-false":
+		return primary(func(*finder, *node) bool { return false }), nil
+	case "-name", "-iname", "-path", "-ipath", "-wholename", "-iwholename":
+		return p.parsePattern(arg)
+	case "-type":
+		return p.parseType(arg)
+	case "-size":
+		return p.parseSize(arg)
+	case "-mtime":
+		return p.parseMtime(arg)
+	case "-newer":
+		return p.parseNewer(arg)
+
+	case "-print":
+		p.printed = true
+		return primary(printPath), nil
+	case "-print0":
+		p.printed = true
+		return primary(printPath0), nil
+	case "-prune":
+		return primary(prune), nil
+	case "-delete":
+		p.printed = true
+		p.f.depthFirst = true // a directory can only be deleted once it is empty
+		return primary(remove), nil
+	case "-exec":
+		p.printed = true
+		return p.parseExec(arg)
+
+	case ")":
+		return nil, fmt.Errorf("invalid expression; unexpected ')'")
+	}
+	return nil, fmt.Errorf("unknown predicate `%s'", arg)
+}
+
+// test returns the primary of a test, which evaluates p against each node, or
+// against the node a symbolic link refers to when tests follow it.
+func test(p match.Predicate) expr {
+	filter, follow := match.Filter(p), match.Filter(match.Follow(p))
+	return primary(func(f *finder, n *node) bool {
+		evaluate := filter
+		if n.follow {
+			evaluate = follow
+		}
+		// Tests match the pathname find(1) prints, which leads to the same node.
+		ok, err := evaluate(n.path, n.de)
+		if err != nil {
+			f.report(err)
+			return false
+		}
+		return ok
+	})
+}
+
+func (p *parser) parsePattern(name string) (expr, error) {
+	pattern, err := p.next(name)
+	if err != nil {
+		return nil, err
+	}
+	fold := name[1] == 'i'
+	if strings.HasSuffix(name, "name") && !strings.HasSuffix(name, "wholename") {
+		return test(match.Glob(pattern, fold)), nil
+	}
+	return test(match.PathGlob(pattern, fold)), nil
+}
+
+// modeTypes maps the arguments of -type to mode type bits.
+var modeTypes = map[byte]os.FileMode{
+	'b': os.ModeDevice,
+	'c': os.ModeDevice | os.ModeCharDevice,
+	'd': os.ModeDir,
+	'f': 0,
+	'l': os.ModeSymlink,
+	'p': os.ModeNamedPipe,
+	's': os.ModeSocket,
+}
+
+func (p *parser) parseType(name string) (expr, error) {
+	arg, err := p.next(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []os.FileMode
+	for _, t := range strings.Split(arg, ",") {
+		if len(t) != 1 {
+			return nil, fmt.Errorf("unknown argument to -type: %s", arg)
+		}
+		mt, ok := modeTypes[t[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown argument to -type: %s", arg)
+		}
+		types = append(types, mt)
+	}
+	return test(match.Type(types...)), nil
+}
+
+// compare is the numeric argument of a primary, which find(1) compares values
+// to, and which is either "+n" for greater than n, "-n" for less than n, or "n"
+// for exactly n.
+type compare struct {
+	sign byte // '+', '-', or 0
+	n    int64
+}
+
+// parseCompare parses the numeric argument arg of a primary, whose suffix, when
+// permitted, is returned.
+func parseCompare(name, arg string, suffixes string) (compare, byte, error) {
+	var c compare
+	var suffix byte
+	s := arg
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		c.sign, s = s[0], s[1:]
+	}
+	if s != "" && strings.IndexByte(suffixes, s[len(s)-1]) >= 0 {
+		suffix, s = s[len(s)-1], s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return c, 0, fmt.Errorf("invalid argument `%s' to `%s'", arg, name)
+	}
+	c.n = n
+	return c, suffix, nil
+}
+
+// sizeUnits maps the suffixes of the argument of -size to their sizes in bytes.
+var sizeUnits = map[byte]int64{
+	0:   512,
+	'b': 512,
+	'c': 1,
+	'w': 2,
+	'k': 1 << 10,
+	'M': 1 << 20,
+	'G': 1 << 30,
+}
+
+func (p *parser) parseSize(name string) (expr, error) {
+	arg, err := p.next(name)
+	if err != nil {
+		return nil, err
+	}
+	c, suffix, err := parseCompare(name, arg, "bcwkMG")
+	if err != nil {
+		return nil, err
+	}
+	unit := sizeUnits[suffix]
+
+	// Like find(1), compare the size rounded up to the next unit, so n units
+	// are the sizes above n-1 units, up to and including n units.
+	switch c.sign {
+	case '+':
+		return test(match.Size(c.n*unit+1, -1)), nil
+	case '-':
+		if c.n == 0 {
+			return test(match.Or()), nil // no size is less than zero units
+		}
+		return test(match.Size(0, (c.n-1)*unit)), nil
+	default:
+		return test(match.Size((c.n-1)*unit+1, c.n*unit)), nil
+	}
+}
+
+func (p *parser) parseMtime(name string) (expr, error) {
+	arg, err := p.next(name)
+	if err != nil {
+		return nil, err
+	}
+	c, _, err := parseCompare(name, arg, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Like find(1), ignore any fractional part of the number of days since
+	// the modification, so n days are the times after n+1 days ago, up to and
+	// including n days ago. Days are counted in UTC to always last 24 hours.
+	daysAgo := func(days int64) time.Time {
+		return p.f.now.UTC().AddDate(0, 0, -int(days)).Add(time.Nanosecond)
+	}
+	switch c.sign {
+	case '+':
+		return test(match.ModTime(time.Time{}, daysAgo(c.n+1))), nil
+	case '-':
+		return test(match.ModTime(daysAgo(c.n), time.Time{})), nil
+	default:
+		return test(match.ModTime(daysAgo(c.n+1), daysAgo(c.n))), nil
+	}
+}
+
+func (p *parser) parseNewer(name string) (expr, error) {
+	arg, err := p.next(name)
+	if err != nil {
+		return nil, err
+	}
+	stat := os.Lstat
+	if p.f.follow == 'H' || p.f.follow == 'L' {
+		stat = os.Stat
+	}
+	fi, err := stat(arg)
+	if err != nil {
+		return nil, err
+	}
+	return test(match.ModTime(fi.ModTime().Add(time.Nanosecond), time.Time{})), nil
+}
+
+func printPath(f *finder, n *node) bool {
+	_, _ = f.out.WriteString(n.path)
+	_ = f.out.WriteByte('\n')
+	return true
+}
+
+func printPath0(f *finder, n *node) bool {
+	_, _ = f.out.WriteString(n.path)
+	_ = f.out.WriteByte(0)
+	return true
+}
+
+func prune(_ *finder, n *node) bool {
+	n.prune = true
+	return true
+}
+
+func remove(f *finder, n *node) bool {
+	if n.path == "." {
+		return true // like find(1), never delete the current directory
+	}
+	if err := os.Remove(n.osPathname); err != nil {
+		f.report(fmt.Errorf("cannot delete %s: %s", n.path, err))
+		return false
+	}
+	return true
+}
+
+func (p *parser) parseExec(name string) (expr, error) {
+	var command []string
+	for {
+		arg, err := p.next(name)
+		if err != nil {
+			return nil, err
+		}
+		if arg == ";" {
+			break
+		}
+		if arg == "+" && len(command) > 1 && command[len(command)-1] == "{}" {
+			b := &batch{command: command[:len(command)-1]}
+			p.f.batches = append(p.f.batches, b)
+			return primary(b.add), nil
+		}
+		command = append(command, arg)
+	}
+	if len(command) == 0 {
+		return nil, fmt.Errorf("missing argument to %s", name)
+	}
+
+	return primary(func(f *finder, n *node) bool {
+		args := make([]string, len(command))
+		for i, arg := range command {
+			args[i] = strings.Replace(arg, "{}", n.path, -1)
+		}
+		return f.execute(args)
+	}), nil
+}
+
+// execute runs the command specified by args, sharing the standard output and
+// standard error of the program, and reports whether it succeeded.
+func (f *finder) execute(args []string) bool {
+	if err := f.out.Flush(); err != nil {
+		f.report(err)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, f.stdout, f.stderr
+	err := cmd.Run()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		f.report(err)
+	}
+	return err == nil
+}
+
+// maxBatchSize is the number of bytes of pathnames after which a batch of
+// -exec ... {} + runs its command, well below the argument size limits of
+// operating systems.
+const maxBatchSize = 128 << 10
+
+// batch is the command of -exec ... {} +, which is run with as many pathnames
+// as possible at once.
+type batch struct {
+	command []string
+	paths   []string
+	size    int // number of bytes of paths
+}
+
+// add adds the pathname of n to the batch, running the command when the batch
+// is full. Like find(1), it is always true.
+func (b *batch) add(f *finder, n *node) bool {
+	b.paths = append(b.paths, n.path)
+	if b.size += len(n.path) + 1; b.size >= maxBatchSize {
+		b.run(f)
+	}
+	return true
+}
+
+// run runs the command with the pathnames added to the batch, if any, and
+// causes the program to exit with a non-zero status when it fails.
+func (b *batch) run(f *finder) {
+	if len(b.paths) == 0 {
+		return
+	}
+	args := append(append([]string(nil), b.command...), b.paths...)
+	b.paths, b.size = b.paths[:0], 0
+	if !f.execute(args) {
+		f.status = 1
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karrick/godirwalk"
+)
+
+// gnuFind returns the pathname of GNU find, skipping the test when it is not
+// available, because other implementations of find(1) differ in their output.
+func gnuFind(t *testing.T) string {
+	t.Helper()
+	pathname, err := exec.LookPath("find")
+	if err != nil {
+		t.Skip("find not found")
+	}
+	output, err := exec.Command(pathname, "--version").Output()
+	if err != nil || !bytes.Contains(output, []byte("GNU findutils")) {
+		t.Skip("GNU find not found")
+	}
+	return pathname
+}
+
+// generateTree creates a directory tree exercising the primaries of gfind in
+// the directory osDirname.
+func generateTree(t *testing.T, osDirname string) {
+	t.Helper()
+
+	now := time.Now()
+	files := []struct {
+		name string
+		size int
+		age  time.Duration
+	}{
+		{".hidden", 1, 0},
+		{"README.md", 600, 0},
+		{"a/[x].txt", 0, 0},
+		{"a/b/c/deep.go", 2000, 36 * time.Hour},
+		{"a/b/main.go", 511, 12 * time.Hour},
+		{"a/b/main_test.go", 513, 12 * time.Hour},
+		{"a/empty.txt", 0, 100 * 24 * time.Hour},
+		{"big.bin", 3 << 20, 0},
+		{"old/log.1", 1024, 400 * 24 * time.Hour},
+		{"old/log.2", 1025, 60 * time.Hour},
+		{"vendor/lib/lib.go", 10, 0},
+		{"with space/Mixed.GO", 1, 0},
+	}
+	for _, f := range files {
+		osPathname := filepath.Join(osDirname, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(osPathname), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(osPathname, bytes.Repeat([]byte("x"), f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(-f.age)
+		if err := os.Chtimes(osPathname, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"a/void", "nest/void"} {
+		if err := os.MkdirAll(filepath.Join(osDirname, filepath.FromSlash(name)), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	symlinks := []struct{ name, target string }{
+		{"a/b/loop", ".."},
+		{"broken", "nowhere"},
+		{"link-to-a", "a"},
+		{"link-to-readme", "README.md"},
+	}
+	for _, s := range symlinks {
+		if err := os.Symlink(s.target, filepath.Join(osDirname, filepath.FromSlash(s.name))); err != nil {
+			t.Skipf("cannot create symbolic link: %s", err)
+		}
+	}
+}
+
+// runFind runs the find(1) at pathname with args in the directory osDirname,
+// and returns its output along with its exit status.
+func runFind(t *testing.T, pathname, osDirname string, args []string) (string, int) {
+	t.Helper()
+	var stdout bytes.Buffer
+	cmd := exec.Command(pathname, args...)
+	cmd.Dir, cmd.Stdout = osDirname, &stdout
+	err := cmd.Run()
+	if ee, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), ee.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), 0
+}
+
+// runGfind runs gfind with args in the directory osDirname, and returns its
+// output along with its exit status.
+func runGfind(t *testing.T, osDirname string, args []string) (string, int) {
+	t.Helper()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(osDirname); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.Chdir(cwd); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	var stdout bytes.Buffer
+	status := run("gfind", args, &stdout, ioutil.Discard)
+	return stdout.String(), status
+}
+
+// sortedRecords returns the records of output, which are terminated by either
+// newline or NUL characters, sorted, so that the listings of two copies of a
+// tree can be compared even though the file system may return the entries of
+// their directories in different orders. The output of find(1) and gfind for
+// the same tree is compared as is, because both list the entries of each
+// directory in the order the file system returns them.
+func sortedRecords(output string) string {
+	terminator := "\n"
+	if strings.Contains(output, "\x00") {
+		terminator = "\x00"
+	}
+	records := strings.SplitAfter(output, terminator)
+	sort.Strings(records)
+	return strings.Join(records, "")
+}
+
+func TestGolden(t *testing.T) {
+	find := gnuFind(t)
+
+	testroot, err := ioutil.TempDir(os.TempDir(), "gfind-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testroot)
+
+	generateTree(t, testroot)
+	if err = ioutil.WriteFile(filepath.Join(testroot, "reference"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	reference := time.Now().Add(-24 * time.Hour)
+	if err = os.Chtimes(filepath.Join(testroot, "reference"), reference, reference); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := [][]string{
+		{},
+		{"."},
+		{"./"},
+		{"a", "old/"},
+		{"a/b/main.go"},
+		{"nonexistent", "a/b"},
+		{"-name", "*.go"},
+		{"-name", "[[]x].txt"},
+		{"-name", "[!m]*"},
+		{"-name", ".*"},
+		{"-iname", "*.go"},
+		{"-path", "./a/*/c*"},
+		{"-ipath", "*WITH*"},
+		{"-type", "f"},
+		{"-type", "d"},
+		{"-type", "l"},
+		{"-type", "f,l"},
+		{"-size", "+1"},
+		{"-size", "-2"},
+		{"-size", "1"},
+		{"-size", "1k"},
+		{"-size", "+1024c"},
+		{"-size", "-1M"},
+		{"-size", "+2M"},
+		{"-mtime", "0"},
+		{"-mtime", "1"},
+		{"-mtime", "+1"},
+		{"-mtime", "-3"},
+		{"-mtime", "+99"},
+		{"-newer", "reference"},
+		{"-maxdepth", "0"},
+		{"-maxdepth", "1"},
+		{"-maxdepth", "2", "-type", "d"},
+		{"-maxdepth", "1", "-depth"},
+		{"-maxdepth", "2", "-depth"},
+		{"-depth", "-name", "b"},
+		{"-name", "vendor", "-prune", "-o", "-name", "*.go", "-print"},
+		{"(", "-name", "a", "-o", "-name", "old", ")", "-prune"},
+		{"!", "-type", "d", "-a", "-not", "-name", "*.go"},
+		{"-name", "*.go", "-o", "-name", "*.md", "-and", "-print"},
+		{"-name", "*.go", ",", "-name", "*.md"},
+		{"-type", "f", "-print0"},
+		{"-name", "*.go", "-exec", "echo", "found:{}", ";"},
+		{"-name", "*.go", "-exec", "printf", "%s\\n", "{}", "+"},
+		{"-name", "main*", "-exec", "test", "-s", "{}", ";", "-print"},
+		{"-xdev", "-type", "d"},
+		{"-L", ".", "-type", "d"},
+		{"-L", ".", "-type", "l"},
+		{"-L", ".", "-depth", "-type", "d"},
+		{"-L", ".", "-name", "link-to-a", "-prune", "-o", "-print"},
+		{"-L", "link-to-a", "-name", "*.go"},
+		{"-H", "link-to-a", "-name", "*.go"},
+		{"-P", "link-to-a"},
+		{"-H", ".", "-name", "link-*", "-type", "l"},
+		{"-L", ".", "-name", "link-to-readme", "-type", "f", "-size", "+1"},
+	}
+
+	for _, args := range cases {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			want, wantStatus := runFind(t, find, testroot, args)
+			got, gotStatus := runGfind(t, testroot, args)
+			if got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+			if gotStatus != wantStatus {
+				t.Errorf("GOT: status %v; WANT: status %v", gotStatus, wantStatus)
+			}
+		})
+	}
+}
+
+// mountParent returns a directory containing a mount point, skipping the test
+// when none of a few well known directories contains one.
+func mountParent(t *testing.T) string {
+	t.Helper()
+	for _, osDirname := range []string{"/sys/fs", "/dev"} {
+		parent, err := godirwalk.NewDirent(osDirname)
+		if err != nil {
+			continue
+		}
+		device, err := parent.Device()
+		if err != nil {
+			continue
+		}
+		names, err := parent.ReadDirnames(nil)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			child, err := godirwalk.NewDirent(filepath.Join(osDirname, name))
+			if err != nil || !child.IsDir() {
+				continue
+			}
+			if childDevice, err := child.Device(); err == nil && childDevice != device {
+				return osDirname
+			}
+		}
+	}
+	t.Skip("no mount point found")
+	return ""
+}
+
+func TestGoldenMountPoint(t *testing.T) {
+	find := gnuFind(t)
+	osDirname := mountParent(t)
+
+	cases := [][]string{
+		{"-xdev"},
+		{"-xdev", "-depth"},
+		{"-mount", "-depth", "-type", "d"},
+		{"-xdev", "-depth", "-maxdepth", "1"},
+	}
+
+	for _, args := range cases {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			want, wantStatus := runFind(t, find, osDirname, args)
+			got, gotStatus := runGfind(t, osDirname, args)
+			if got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+			if gotStatus != wantStatus {
+				t.Errorf("GOT: status %v; WANT: status %v", gotStatus, wantStatus)
+			}
+		})
+	}
+}
+
+func TestGoldenDelete(t *testing.T) {
+	find := gnuFind(t)
+
+	cases := [][]string{
+		{"-name", "*.go", "-delete"},
+		{"-type", "d", "-name", "void", "-delete"},
+		{"-name", "a", "-delete"},
+		{".", "-delete"},
+		{"-maxdepth", "2", "-delete"},
+		{"-maxdepth", "2", "-delete", "-print"},
+	}
+
+	for _, args := range cases {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			// Each implementation deletes from its own copy of the tree.
+			var outputs, remaining [2]string
+			var statuses [2]int
+			for i := range outputs {
+				testroot, err := ioutil.TempDir(os.TempDir(), "gfind-")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(testroot)
+				generateTree(t, testroot)
+
+				if i == 0 {
+					outputs[i], statuses[i] = runFind(t, find, testroot, args)
+				} else {
+					outputs[i], statuses[i] = runGfind(t, testroot, args)
+				}
+				remaining[i], _ = runFind(t, find, testroot, nil)
+			}
+
+			if got, want := sortedRecords(remaining[1]), sortedRecords(remaining[0]); got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+			if got, want := outputs[1], outputs[0]; got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+			if got, want := statuses[1], statuses[0]; got != want {
+				t.Errorf("GOT: status %v; WANT: status %v", got, want)
+			}
+		})
+	}
+}
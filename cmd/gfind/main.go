@@ -0,0 +1,295 @@
+/*
+gfind searches directory trees like find(1), using godirwalk to read
+directories.
+
+	gfind [-H] [-L] [-P] [starting-point...] [expression]
+
+It supports the commonly used part of the grammar of GNU find:
+
+	Options:   -depth -maxdepth N -xdev -mount
+	Tests:     -name PATTERN -iname PATTERN -path PATTERN -ipath PATTERN
+	           -type [bcdflps] -size [+-]N[bcwkMG] -mtime [+-]N
+	           -newer FILE -true -false
+	Actions:   -print -print0 -prune -delete
+	           -exec COMMAND ; -exec COMMAND {} +
+	Operators: ( EXPR ) ! EXPR -not EXPR EXPR -a EXPR EXPR -and EXPR
+	           EXPR -o EXPR EXPR -or EXPR EXPR , EXPR
+
+When the expression contains no action other than -prune, gfind prints the
+pathname of every node for which the expression is true. Patterns are matched
+like fnmatch(3), except for character classes such as [:alpha:], which are not
+supported. Like find, gfind lists directories in the order the file system
+returns their entries.
+
+Because gfind only uses the standard library, building it with CGO_ENABLED=0
+produces a static executable, suitable for minimal containers.
+*/
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karrick/godirwalk"
+)
+
+func main() {
+	programName, err := os.Executable()
+	if err != nil {
+		programName = os.Args[0]
+	}
+	programName = filepath.Base(programName)
+
+	os.Exit(run(programName, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run searches the directory trees specified by args, writing the output of
+// the expression to stdout and diagnostics to stderr, and returns the exit
+// status of the program.
+func run(programName string, args []string, stdout, stderr io.Writer) int {
+	f := &finder{
+		programName: programName,
+		stdout:      stdout,
+		stderr:      stderr,
+		out:         bufio.NewWriter(stdout),
+		maxDepth:    -1,
+		now:         time.Now(),
+	}
+
+	// Options that precede the starting points select which symbolic links
+	// are followed, and the last one wins.
+	for len(args) > 0 {
+		switch args[0] {
+		case "-H", "-L", "-P":
+			f.follow = args[0][1]
+			args = args[1:]
+			continue
+		}
+		break
+	}
+
+	var roots []string
+	for len(args) > 0 && !isExpression(args[0]) {
+		roots = append(roots, args[0])
+		args = args[1:]
+	}
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	e, err := f.parse(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", programName, err)
+		return 1
+	}
+	f.expr = e
+
+	options := &godirwalk.Options{
+		AllowNonDirectory: true,
+		Unsorted:          true,
+		OneFileSystem:     f.xdev,
+		ScratchBuffer:     make([]byte, godirwalk.MinimumScratchBufferSize),
+		ChildrenCallback:  f.enter,
+	}
+	if f.maxDepth > 0 {
+		options.MaxDepth = f.maxDepth // zero is handled by visit
+	}
+	switch f.follow {
+	case 'L':
+		options.SymlinkPolicy = godirwalk.FollowAlways
+		options.DanglingSymlinkCallback = func(string, string) godirwalk.ErrorAction {
+			return godirwalk.SkipNode // tested like any other symbolic link
+		}
+	case 'H':
+		options.SymlinkPolicy = godirwalk.FollowRoot
+	default:
+		options.SymlinkPolicy = godirwalk.FollowNever
+	}
+
+	for _, pathname := range roots {
+		if pathname == "" {
+			f.report(errors.New("cannot search the empty pathname"))
+			continue
+		}
+		r := newRoot(pathname)
+		options.Callback = func(osPathname string, de *godirwalk.Dirent) error {
+			return f.visit(r, osPathname, de)
+		}
+		options.ErrorCallback = func(osPathname string, err error) godirwalk.ErrorAction {
+			f.fail(r, osPathname, err)
+			return godirwalk.SkipNode
+		}
+		if f.depthFirst {
+			options.PostChildrenCallback = func(osPathname string, de *godirwalk.Dirent) error {
+				f.settle() // the last child of the directory comes before it
+				f.evaluate(f.newNode(r, osPathname, de))
+				return nil
+			}
+		}
+		if err := godirwalk.Walk(r.clean, options); err != nil {
+			f.report(err)
+		}
+		f.settle()
+	}
+
+	for _, b := range f.batches {
+		b.run(f)
+	}
+	if err := f.out.Flush(); err != nil {
+		f.report(err)
+	}
+	return f.status
+}
+
+// isExpression reports whether arg starts the expression rather than being a
+// starting point.
+func isExpression(arg string) bool {
+	return arg == "(" || arg == "!" || len(arg) > 1 && arg[0] == '-'
+}
+
+// finder holds the state of a search.
+type finder struct {
+	programName    string
+	stdout, stderr io.Writer
+	out            *bufio.Writer // buffers stdout
+	expr           expr
+	batches        []*batch  // commands of -exec ... {} +
+	follow         byte      // 'H', 'L', or 'P' for the option selecting symbolic links to follow
+	depthFirst     bool      // whether directories are evaluated after their contents
+	maxDepth       int       // maximum depth of evaluated nodes, or -1
+	xdev           bool      // whether to stay on the file system of each starting point
+	pending        *node     // directory visited but not yet evaluated
+	now            time.Time // time the search started
+	status         int       // exit status
+}
+
+// report writes err to stderr, and causes the program to exit with a non-zero
+// status.
+func (f *finder) report(err error) {
+	_ = f.out.Flush() // keep output and diagnostics in order
+	fmt.Fprintf(f.stderr, "%s: %s\n", f.programName, err)
+	f.status = 1
+}
+
+// root is a starting point of the search.
+type root struct {
+	pathname string // pathname as provided, which find(1) prints
+	clean    string // cleaned pathname provided to godirwalk
+	prefix   string // pathname of the children of the starting point, without their names
+	skip     int    // length of the prefix of the children pathnames godirwalk provides
+}
+
+// newRoot returns the starting point specified by pathname, whose descendants
+// are named like find(1) names them, by appending their names to pathname.
+func newRoot(pathname string) *root {
+	r := &root{pathname: pathname, clean: filepath.Clean(pathname), prefix: pathname}
+	switch {
+	case r.clean == ".":
+		// godirwalk provides the children of "." without a prefix.
+	case os.IsPathSeparator(r.clean[len(r.clean)-1]):
+		r.skip = len(r.clean)
+	default:
+		r.skip = len(r.clean) + 1
+	}
+	if !os.IsPathSeparator(pathname[len(pathname)-1]) {
+		r.prefix += string(filepath.Separator)
+	}
+	return r
+}
+
+// path returns the pathname find(1) prints for the node below the starting
+// point whose pathname godirwalk provides as osPathname.
+func (r *root) path(osPathname string) string {
+	if osPathname == r.clean {
+		return r.pathname
+	}
+	return r.prefix + osPathname[r.skip:]
+}
+
+// node is a file system node the expression is evaluated against.
+type node struct {
+	path       string // pathname as find(1) prints it
+	osPathname string // pathname provided by godirwalk
+	de         *godirwalk.Dirent
+	follow     bool // whether tests follow a symbolic link
+	prune      bool // whether -prune was evaluated
+}
+
+// newNode returns the node at osPathname, below the starting point r.
+func (f *finder) newNode(r *root, osPathname string, de *godirwalk.Dirent) *node {
+	n := &node{path: r.path(osPathname), osPathname: osPathname, de: de, follow: f.follow == 'L'}
+	if osPathname == r.clean {
+		n.follow = n.follow || f.follow == 'H'
+	}
+	return n
+}
+
+// visit is the Callback function of the walk of the starting point r. Rather
+// than predicting whether the walk descends into a directory, it leaves the
+// directory pending until the walk either reads it, invoking enter, or moves
+// on to another node.
+func (f *finder) visit(r *root, osPathname string, de *godirwalk.Dirent) error {
+	f.settle()
+	n := f.newNode(r, osPathname, de)
+
+	if osPathname == r.clean && f.maxDepth == 0 {
+		f.evaluate(n)
+		return godirwalk.SkipThis
+	}
+	if de.IsDir() || n.follow && de.IsSymlink() {
+		f.pending = n
+		return nil
+	}
+	f.evaluate(n)
+	return nil
+}
+
+// enter is the ChildrenCallback function of the walk, which is invoked once the
+// walk has read the pending directory. Unless directories are evaluated after
+// their contents, it evaluates the directory, and skips its contents when the
+// expression pruned it.
+func (f *finder) enter(_ string, children godirwalk.Dirents) (godirwalk.Dirents, error) {
+	n := f.pending
+	f.pending = nil
+	if n == nil || f.depthFirst {
+		return children, nil // evaluated by the PostChildrenCallback function
+	}
+	f.evaluate(n)
+	if n.prune {
+		return nil, godirwalk.SkipThis
+	}
+	return children, nil
+}
+
+// settle evaluates the pending directory, which the walk did not read, such as
+// a mount point when staying on one file system, or a directory at the maximum
+// depth.
+func (f *finder) settle() {
+	if n := f.pending; n != nil {
+		f.pending = nil
+		f.evaluate(n)
+	}
+}
+
+// fail is the ErrorCallback function of the walk of the starting point r. Like
+// find(1), it reports a symbolic link that leads back to one of its ancestors
+// rather than evaluating it.
+func (f *finder) fail(r *root, osPathname string, err error) {
+	if e, ok := err.(*godirwalk.ErrSymlinkCycle); ok && f.pending != nil && f.pending.osPathname == osPathname {
+		f.pending = nil
+		f.report(fmt.Errorf("file system loop detected; %s is part of the same file system loop as %s", r.path(e.Pathname), r.path(e.Ancestor)))
+		return
+	}
+	f.settle()
+	f.report(err)
+}
+
+// evaluate evaluates the expression against n.
+func (f *finder) evaluate(n *node) {
+	_ = f.expr.eval(f, n)
+}
@@ -2,7 +2,9 @@ package godirwalk
 
 import (
 	"os"
+	"path"
 	"path/filepath"
+	"sync"
 )
 
 // Dirent stores the name and file system mode type of discovered file system
@@ -11,8 +13,41 @@ type Dirent struct {
 	name     string      // base name of the file system entry.
 	path     string      // path name of the file system entry.
 	modeType os.FileMode // modeType is the type of file system entry.
+	toDir    linkToDir   // toDir caches whether a symbolic link refers to a directory.
+	reader   dirReader   // reader reads the entry's file system; nil for the OS.
+	info     os.FileInfo // info caches the file information returned by Info.
+	inode    uint64      // inode is the inode number of the file system entry.
+	device   *lazyDevice // device of the parent directory; nil when not read from one.
 }
 
+// lazyDevice obtains the device number of a directory the first time it is
+// needed. It is shared by every Dirent read from that directory, so the
+// directory is queried at most once.
+type lazyDevice struct {
+	once sync.Once
+	dev  uint64
+	err  error
+}
+
+func (ld *lazyDevice) get(osDirname string) (uint64, error) {
+	ld.once.Do(func() {
+		var fi os.FileInfo
+		if fi, ld.err = os.Stat(osDirname); ld.err == nil {
+			ld.dev = deviceFromFileInfo(fi)
+		}
+	})
+	return ld.dev, ld.err
+}
+
+// linkToDir records whether a symbolic link refers to a directory, once known.
+type linkToDir uint8
+
+const (
+	linkToDirUnknown linkToDir = iota
+	linkToDirTrue
+	linkToDirFalse
+)
+
 // NewDirent returns a newly initialized Dirent structure, or an error.  This
 // function does not follow symbolic links.
 //
@@ -20,14 +55,16 @@ type Dirent struct {
 // functions in this library that read and walk directories, but is provided,
 // however, for the occasion when a program needs to create a Dirent.
 func NewDirent(osPathname string) (*Dirent, error) {
-	modeType, err := modeType(osPathname)
+	fi, err := os.Lstat(osPathname)
 	if err != nil {
 		return nil, err
 	}
 	return &Dirent{
 		name:     filepath.Base(osPathname),
 		path:     filepath.Dir(osPathname),
-		modeType: modeType,
+		modeType: fi.Mode() & os.ModeType,
+		info:     fi,
+		inode:    inodeFromFileInfo(fi),
 	}, nil
 }
 
@@ -40,7 +77,8 @@ func (de Dirent) IsDir() bool { return de.modeType&os.ModeDir != 0 }
 // IsDirOrSymlinkToDir returns true if and only if the Dirent represents a file
 // system directory, or a symbolic link to a directory. Note that if the Dirent
 // is not a directory but is a symbolic link, this method will resolve by
-// sending a request to the operating system to follow the symbolic link.
+// sending a request to the operating system to follow the symbolic link,
+// unless the walk that produced the Dirent already followed it.
 func (de Dirent) IsDirOrSymlinkToDir() (bool, error) {
 	if de.IsDir() {
 		return true, nil
@@ -48,14 +86,113 @@ func (de Dirent) IsDirOrSymlinkToDir() (bool, error) {
 	if !de.IsSymlink() {
 		return false, nil
 	}
+	switch de.toDir {
+	case linkToDirTrue:
+		return true, nil
+	case linkToDirFalse:
+		return false, nil
+	}
 	// Does this symlink point to a directory?
-	info, err := os.Stat(filepath.Join(de.path, de.name))
+	info, err := de.Stat()
 	if err != nil {
 		return false, err
 	}
 	return info.IsDir(), nil
 }
 
+// setLinkToDir remembers whether the symbolic link refers to a directory.
+func (de *Dirent) setLinkToDir(isDir bool) {
+	if isDir {
+		de.toDir = linkToDirTrue
+	} else {
+		de.toDir = linkToDirFalse
+	}
+}
+
+// Info returns the file information for the file system entry, which does not
+// follow symbolic links. The file system is only queried the first time Info is
+// invoked, and the result is remembered for subsequent invocations, so the
+// returned information describes the entry as it was at that time. Along with
+// Name, IsDir, and Type, this method allows *Dirent to satisfy the fs.DirEntry
+// interface.
+func (de *Dirent) Info() (os.FileInfo, error) {
+	if de.info == nil {
+		var fi os.FileInfo
+		var err error
+		if de.reader != nil {
+			fi, err = de.reader.lstat(path.Join(de.path, de.name))
+		} else {
+			fi, err = os.Lstat(filepath.Join(de.path, de.name))
+		}
+		if err != nil {
+			return nil, err
+		}
+		de.info = fi
+	}
+	return de.info, nil
+}
+
+// Stat returns the file information for the file system entry, following
+// symbolic links. Unlike Info, it does not remember the result, so it queries
+// the file system each time it is invoked, unless Info already obtained the
+// information for an entry that is not a symbolic link.
+func (de Dirent) Stat() (os.FileInfo, error) {
+	if de.info != nil && de.info.Mode()&os.ModeSymlink == 0 {
+		return de.info, nil
+	}
+	if de.reader != nil {
+		return de.reader.stat(path.Join(de.path, de.name))
+	}
+	return os.Stat(filepath.Join(de.path, de.name))
+}
+
+// ReadDirnames returns the names of the immediate descendants of the directory
+// represented by the Dirent, in no particular order. The directory is read from
+// the same file system as the Dirent, which is that of the operating system
+// unless the Dirent was provided by WalkFS. Like the ReadDirnames function, it
+// allocates a scratch buffer when scratchBuffer is nil or too small.
+func (de Dirent) ReadDirnames(scratchBuffer []byte) ([]string, error) {
+	if de.reader == nil {
+		return ReadDirnames(filepath.Join(de.path, de.name), scratchBuffer)
+	}
+	deChildren, err := de.reader.readDirents(path.Join(de.path, de.name))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(deChildren))
+	for i, child := range deChildren {
+		names[i] = child.name
+	}
+	return names, nil
+}
+
+// Device returns the device number of the file system containing the file
+// system entry. For entries read from a directory, it is obtained by querying
+// the directory the first time Device is invoked for any of its entries, so
+// the device number of a mount point is that of the file system containing
+// the mount point rather than that of the file system mounted on it. For other
+// entries, it is obtained from the Info method. On Windows, and for file
+// systems that do not provide one, the device number is always 0.
+func (de *Dirent) Device() (uint64, error) {
+	if de.device != nil {
+		return de.device.get(de.path)
+	}
+	fi, err := de.Info()
+	if err != nil {
+		return 0, err
+	}
+	return deviceFromFileInfo(fi), nil
+}
+
+// Inode returns the inode number of the file system entry, as provided by the
+// operating system when it read the directory containing the entry, so no
+// additional query of the file system is required. Like the device number of
+// a mount point, the inode number of a mount point is that of the directory
+// mounted upon rather than that of the root of the mounted file system. On
+// Windows, and for file systems that do not provide one, the inode number is
+// always 0.
+func (de Dirent) Inode() uint64 { return de.inode }
+
 // IsRegular returns true if and only if the Dirent represents a regular file.
 // That is, it ensures that no mode type bits are set.
 func (de Dirent) IsRegular() bool { return de.modeType&os.ModeType == 0 }
@@ -79,6 +216,11 @@ func (de Dirent) IsDevice() bool { return de.modeType&os.ModeDevice != 0 }
 //    information about files can be moved from one system to another portably.
 func (de Dirent) ModeType() os.FileMode { return de.modeType }
 
+// Type returns the mode bits that specify the file system node type, exactly
+// like ModeType. Along with Name, IsDir, and Info, this method allows *Dirent to
+// satisfy the fs.DirEntry interface.
+func (de Dirent) Type() os.FileMode { return de.modeType }
+
 // Name returns the base name of the file system entry.
 func (de Dirent) Name() string { return de.name }
 
@@ -87,6 +229,11 @@ func (de *Dirent) reset() {
 	de.name = ""
 	de.path = ""
 	de.modeType = 0
+	de.toDir = linkToDirUnknown
+	de.reader = nil
+	de.info = nil
+	de.inode = 0
+	de.device = nil
 }
 
 // Dirents represents a slice of Dirent pointers, which are sortable by base
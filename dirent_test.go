@@ -1,8 +1,10 @@
 package godirwalk
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -140,3 +142,191 @@ func TestDirent(t *testing.T) {
 		})
 	})
 }
+
+func TestDirentInfo(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		osPathname := filepath.Join(scaffolingRoot, "d0", "f1")
+
+		de, err := NewDirent(osPathname)
+		ensureError(t, err)
+
+		if got, want := de.Type(), de.ModeType(); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+
+		fi, err := de.Info()
+		ensureError(t, err)
+
+		if got, want := fi.Name(), "f1"; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := fi.Size(), int64(len(osPathname)+1); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		testroot := filepath.Join(scaffolingRoot, "d0", "symlinks")
+
+		children, err := ReadDirents(testroot, nil)
+		ensureError(t, err)
+
+		var de *Dirent
+		for _, child := range children {
+			if child.Name() == "toD1" {
+				de = child
+			}
+		}
+		if de == nil {
+			t.Fatalf("GOT: nil; WANT: toD1")
+		}
+
+		fi, err := de.Info()
+		ensureError(t, err)
+
+		if got, want := fi.Mode()&os.ModeType, os.ModeSymlink; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+
+		// Ensure the previously obtained information is reused rather than
+		// querying the file system again.
+		fi2, err := de.Info()
+		ensureError(t, err)
+
+		if got, want := fi2, fi; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("symlink to directory remembered by walk", func(t *testing.T) {
+		de, err := NewDirent(filepath.Join(scaffolingRoot, "d0", "symlinks", "toD1"))
+		ensureError(t, err)
+
+		// Pretend the walk found the referent not to be a directory, to
+		// ensure the remembered result is used rather than following the
+		// symbolic link again.
+		de.setLinkToDir(false)
+
+		got, err := de.IsDirOrSymlinkToDir()
+		ensureError(t, err)
+		if want := false; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
+
+func TestDirentStat(t *testing.T) {
+	de, err := NewDirent(filepath.Join(scaffolingRoot, "d0", "symlinks", "toF1"))
+	ensureError(t, err)
+
+	fi, err := de.Stat()
+	ensureError(t, err)
+
+	if got, want := fi.Mode()&os.ModeType, os.FileMode(0); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+
+	fi, err = de.Info()
+	ensureError(t, err)
+
+	if got, want := fi.Mode()&os.ModeType, os.ModeSymlink; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
+
+func TestDirentReadDirnames(t *testing.T) {
+	osDirname := filepath.Join(scaffolingRoot, "d0")
+
+	de, err := NewDirent(osDirname)
+	ensureError(t, err)
+
+	actual, err := de.ReadDirnames(nil)
+	ensureError(t, err)
+
+	expected, err := ReadDirnames(osDirname, nil)
+	ensureError(t, err)
+
+	ensureStringSlicesMatch(t, actual, expected)
+}
+
+func TestDirentInodeAndDevice(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("inode and device numbers are not provided on Windows")
+	}
+
+	testroot := filepath.Join(scaffolingRoot, "d0")
+
+	fi, err := os.Stat(testroot)
+	ensureError(t, err)
+	wantDevice := deviceFromFileInfo(fi)
+
+	ensureDirent := func(t *testing.T, de *Dirent) {
+		t.Helper()
+
+		fi, err := os.Lstat(filepath.Join(testroot, de.Name()))
+		ensureError(t, err)
+
+		if got, want := de.Inode(), inodeFromFileInfo(fi); got != want {
+			t.Errorf("%s: GOT: %v; WANT: %v", de.Name(), got, want)
+		}
+
+		got, err := de.Device()
+		ensureError(t, err)
+		if want := wantDevice; got != want {
+			t.Errorf("%s: GOT: %v; WANT: %v", de.Name(), got, want)
+		}
+	}
+
+	t.Run("ReadDirents", func(t *testing.T) {
+		children, err := ReadDirents(testroot, nil)
+		ensureError(t, err)
+		for _, child := range children {
+			ensureDirent(t, child)
+		}
+	})
+
+	t.Run("Scanner", func(t *testing.T) {
+		scanner, err := NewScanner(testroot)
+		ensureError(t, err)
+		for scanner.Scan() {
+			de, err := scanner.Dirent()
+			ensureError(t, err)
+			ensureDirent(t, de)
+		}
+		ensureError(t, scanner.Err())
+	})
+
+	t.Run("NewDirent", func(t *testing.T) {
+		de, err := NewDirent(testroot)
+		ensureError(t, err)
+
+		if got, want := de.Inode(), inodeFromFileInfo(fi); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+
+		got, err := de.Device()
+		ensureError(t, err)
+		if want := wantDevice; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("hard links", func(t *testing.T) {
+		tempdir, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+		ensureError(t, err)
+		defer os.RemoveAll(tempdir)
+
+		ensureError(t, ioutil.WriteFile(filepath.Join(tempdir, "a"), []byte("a"), 0644))
+		ensureError(t, os.Link(filepath.Join(tempdir, "a"), filepath.Join(tempdir, "b")))
+
+		children, err := ReadDirents(tempdir, nil)
+		ensureError(t, err)
+		if got, want := len(children), 2; got != want {
+			t.Fatalf("GOT: %v; WANT: %v", got, want)
+		}
+
+		if got, want := children[0].Inode(), children[1].Inode(); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
@@ -1,12 +0,0 @@
-module github.com/karrick/godirwalk/examples/find-fast
-
-replace github.com/karrick/godirwalk => ../../
-
-require (
-	github.com/karrick/godirwalk v1.13.5
-	github.com/karrick/golf v1.4.0
-	github.com/mattn/go-isatty v0.0.11
-	golang.org/x/sys v0.0.0-20191210023423-ac6580df4449 // indirect
-)
-
-go 1.13
@@ -39,7 +39,7 @@ func sizes(osDirname string) error {
 				return nil
 			}
 
-			st, err := os.Stat(osPathname)
+			st, err := de.Info()
 			if err != nil {
 				return err
 			}
@@ -58,7 +58,7 @@ func sizes(osDirname string) error {
 			size := sizes.LeaveDirectory()
 			sizes.Accumulate(size) // add this directory's size to parent directory.
 
-			st, err := os.Stat(osPathname)
+			st, err := de.Info()
 
 			switch err {
 			case nil:
@@ -0,0 +1,181 @@
+package godirwalk
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// globSegment is one slash-separated segment of a compiled glob pattern.
+type globSegment struct {
+	pattern    string // pattern the segment matches, as accepted by path.Match
+	literal    bool   // whether pattern has no special characters
+	doublestar bool   // whether the segment is "**", matching any number of segments
+}
+
+// match reports whether the segment matches the single pathname component
+// name.
+func (s globSegment) match(name string) bool {
+	if s.literal {
+		return s.pattern == name
+	}
+	matched, _ := path.Match(s.pattern, name) // pattern validated when compiled
+	return matched
+}
+
+// glob is a compiled Include or Exclude pattern.
+type glob []globSegment
+
+// compileGlob compiles the slash-separated pattern, in which a "**" segment
+// matches any number of pathname components, including none, and every other
+// segment matches a single component as specified by path.Match.
+func compileGlob(pattern string) (glob, error) {
+	var g glob
+	for _, s := range strings.Split(pattern, "/") {
+		switch {
+		case s == "":
+			continue // ignore leading, trailing, and repeated slashes
+		case s == "**":
+			if len(g) > 0 && g[len(g)-1].doublestar {
+				continue // consecutive "**" segments match the same as one
+			}
+			g = append(g, globSegment{doublestar: true})
+		default:
+			if _, err := path.Match(s, ""); err != nil {
+				return nil, fmt.Errorf("cannot compile pattern %q: %s", pattern, err)
+			}
+			g = append(g, globSegment{pattern: s, literal: !strings.ContainsAny(s, `*?[\`)})
+		}
+	}
+	if len(g) == 0 {
+		return nil, fmt.Errorf("cannot compile empty pattern %q", pattern)
+	}
+	return g, nil
+}
+
+// match reports whether the glob matches the relative pathname name, whose
+// components are separated by either a slash or the operating system's path
+// separator.
+func (g glob) match(name string) bool {
+	for len(g) > 0 {
+		if g[0].doublestar {
+			g = g[1:]
+			if len(g) == 0 {
+				return true
+			}
+			// Try matching the rest of the pattern at each remaining
+			// component.
+			for {
+				if g.match(name) {
+					return true
+				}
+				i := indexSeparator(name)
+				if i < 0 {
+					return false
+				}
+				name = name[i+1:]
+			}
+		}
+
+		i := indexSeparator(name)
+		if i < 0 {
+			// The last component of name must match the last segment of the
+			// pattern, other than "**" segments matching no components.
+			return g[0].match(name) && (len(g) == 1 || len(g) == 2 && g[1].doublestar)
+		}
+		if !g[0].match(name[:i]) {
+			return false
+		}
+		g, name = g[1:], name[i+1:]
+	}
+	return false // name has more components than the pattern
+}
+
+// indexSeparator returns the index of the first path separator in name, or -1
+// when there is none.
+func indexSeparator(name string) int {
+	for i := 0; i < len(name); i++ {
+		if name[i] == '/' || name[i] == filepath.Separator {
+			return i
+		}
+	}
+	return -1
+}
+
+// globFilter holds the compiled Include and Exclude patterns of a walk.
+type globFilter struct {
+	include, exclude []glob
+	prefix           int // length of the root pathname prefix of descendants
+}
+
+// newGlobFilter returns the globFilter for a walk rooted at the cleaned
+// pathname root, or nil when the options specify no patterns.
+func newGlobFilter(root string, options *Options) (*globFilter, error) {
+	if len(options.Include) == 0 && len(options.Exclude) == 0 {
+		return nil, nil
+	}
+
+	f := new(globFilter)
+	for _, pattern := range options.Include {
+		g, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, g)
+	}
+	for _, pattern := range options.Exclude {
+		g, err := compileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, g)
+	}
+
+	f.prefix = childPrefix(root)
+	return f, nil
+}
+
+// childPrefix returns the length of the prefix that the pathnames of the
+// descendants of the directory specified by the cleaned pathname osDirname
+// share, so that slicing it off leaves their pathname relative to osDirname.
+func childPrefix(osDirname string) int {
+	// Children are joined to the directory with a separator, except when the
+	// directory already ends with one, and the directory is elided when it is
+	// ".".
+	switch {
+	case osDirname == ".":
+		return 0
+	case strings.HasSuffix(osDirname, "/") || strings.HasSuffix(osDirname, string(filepath.Separator)):
+		return len(osDirname)
+	default:
+		return len(osDirname) + 1
+	}
+}
+
+// excluded reports whether the descendant of the root specified by osPathname
+// matches any of the Exclude patterns.
+func (f *globFilter) excluded(osPathname string) bool {
+	name := osPathname[f.prefix:]
+	for _, g := range f.exclude {
+		if g.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// included reports whether the descendant of the root specified by osPathname
+// matches any of the Include patterns, or whether there are none.
+func (f *globFilter) included(osPathname string) bool {
+	if len(f.include) == 0 {
+		return true
+	}
+	name := osPathname[f.prefix:]
+	for _, g := range f.include {
+		if g.match(name) {
+			return true
+		}
+	}
+	return false
+}
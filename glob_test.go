@@ -0,0 +1,66 @@
+package godirwalk
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.go", "walk.go", true},
+		{"*.go", "cmd/walk.go", false},
+		{"**/*.go", "walk.go", true},
+		{"**/*.go", "cmd/gfind/main.go", true},
+		{"**/*.go", "cmd/gfind/main.c", false},
+		{"cmd/**", "cmd", true},
+		{"cmd/**", "cmd/gfind/main.go", true},
+		{"cmd/**", "examples/cmd", false},
+		{"cmd/**/main.go", "cmd/main.go", true},
+		{"cmd/**/main.go", "cmd/gfind/internal/main.go", true},
+		{"cmd/**/main.go", "cmd/gfind/main.go.orig", false},
+		{"**/node_modules", "node_modules", true},
+		{"**/node_modules", "web/app/node_modules", true},
+		{"**/node_modules", "web/app/node_modules/left-pad", false},
+		{"**", "anything/at/all", true},
+		{"a/**/**/b", "a/x/b", true},
+		{"/vendor/", "vendor", true},
+		{"vendor", "vendor/modules.txt", false},
+		{"f?", "f1", true},
+		{"f[0-4]", "f5", false},
+		{"d*/skip", "d3/skip", true},
+		{"d*/skip", "d3/f4", false},
+	}
+
+	for _, c := range cases {
+		g, err := compileGlob(c.pattern)
+		ensureError(t, err)
+		if got, want := g.match(filepath.FromSlash(c.name)), c.want; got != want {
+			t.Errorf("%q %q: GOT: %v; WANT: %v", c.pattern, c.name, got, want)
+		}
+	}
+}
+
+func TestGlobCompileError(t *testing.T) {
+	for _, pattern := range []string{"", "/", "[", "a/[b/c"} {
+		_, err := compileGlob(pattern)
+		ensureError(t, err, "cannot compile")
+	}
+}
+
+func TestGlobMatchAllocations(t *testing.T) {
+	g, err := compileGlob("**/d*/**/[fz]?")
+	ensureError(t, err)
+	name := filepath.FromSlash("a/b/d3/skip/f5")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if !g.match(name) {
+			t.Fatal("GOT: false; WANT: true")
+		}
+	})
+	if got, want := allocs, 0.0; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package godirwalk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// ignoreRule is a compiled pattern of an ignore file, such as .gitignore.
+type ignoreRule struct {
+	glob    glob
+	negate  bool // whether the pattern re-includes the nodes it matches
+	dirOnly bool // whether the pattern only matches directories
+}
+
+// parseIgnoreRules returns the rules of an ignore file written with the syntax
+// described by gitignore(5). Like git, it skips malformed patterns.
+func parseIgnoreRules(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		line = trimTrailingSpaces(strings.TrimSuffix(line, "\r"))
+		if line == "" || line[0] == '#' {
+			continue // blank lines and comments match nothing
+		}
+
+		var rule ignoreRule
+		if line[0] == '!' {
+			rule.negate, line = true, line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if strings.Contains(line, "/") {
+			// A pattern with a slash other than at its end is anchored to the
+			// directory containing the ignore file.
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line // matches at any depth
+		}
+		if strings.HasSuffix(line, "/**") {
+			// A trailing "/**" matches everything inside a directory, but not
+			// the directory itself.
+			line = line[:len(line)-2] + "*/**"
+		}
+
+		g, err := compileGlob(strings.Replace(line, "[!", "[^", -1))
+		if err != nil {
+			continue
+		}
+		rule.glob = g
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// trimTrailingSpaces returns line without its trailing spaces, except those
+// escaped with a backslash.
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// matchIgnoreRules reports whether any of the rules matches the relative
+// pathname name of a node, which is a directory when isDir is true, and if so,
+// whether the last of those rules, which takes precedence, ignores the node.
+func matchIgnoreRules(rules []ignoreRule, name string, isDir bool) (matched, ignored bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		rule := rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.glob.match(name) {
+			return true, !rule.negate
+		}
+	}
+	return false, false
+}
+
+// ignoreRules are the rules of the ignore files of a directory, which apply to
+// its descendants along with the rules of the directories above it.
+type ignoreRules struct {
+	prefix  int          // length of the pathname prefix of the descendants of the directory
+	rules   []ignoreRule // rules of .gitignore and IgnoreFile, by increasing precedence
+	exclude []ignoreRule // rules of .git/info/exclude, which have the lowest precedence
+	parent  *ignoreRules // rules of the nearest directory above with ignore files
+}
+
+// ignores reports whether the node specified by osPathname, which is a
+// directory when isDir is true, is ignored by the rules of the directory of r,
+// or those of the directories above it. As with git, the rules of a directory
+// take precedence over those of the directories above it, and the rules of
+// .git/info/exclude have the lowest precedence.
+func (r *ignoreRules) ignores(osPathname string, isDir bool) bool {
+	for s := r; s != nil; s = s.parent {
+		if matched, ignored := matchIgnoreRules(s.rules, osPathname[s.prefix:], isDir); matched {
+			return ignored
+		}
+	}
+	for s := r; s != nil; s = s.parent {
+		if matched, ignored := matchIgnoreRules(s.exclude, osPathname[s.prefix:], isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// ignoring reports whether the walk reads ignore files.
+func (ws *walkState) ignoring() bool {
+	return ws.options.GitIgnore || ws.options.IgnoreFile != ""
+}
+
+// ignore returns the ancestor the children of the directory specified by
+// osPathname, open as dirfd, ought to be walked with, given self, the ancestor
+// that would otherwise be used, and parent, the ancestor the directory was
+// walked with. When the directory has ignore files, the returned ancestor
+// holds their rules.
+func (ws *walkState) ignore(dirfd int, osPathname string, parent, self *ancestor) (*ancestor, error) {
+	var inherited *ignoreRules
+	if self != nil {
+		inherited = self.ignore
+	}
+
+	var r *ignoreRules
+	for i, name := range [...]string{".git/info/exclude", ".gitignore", ws.options.IgnoreFile} {
+		if name == "" || i < 2 && !ws.options.GitIgnore {
+			continue
+		}
+		data, err := ws.readFile(dirfd, osPathname, name)
+		if err != nil {
+			if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) || errors.Is(err, syscall.EISDIR) {
+				continue
+			}
+			return nil, err
+		}
+		rules := parseIgnoreRules(data)
+		if len(rules) == 0 {
+			continue
+		}
+		if r == nil {
+			r = &ignoreRules{prefix: childPrefix(osPathname), parent: inherited}
+		}
+		if i == 0 {
+			r.exclude = rules
+		} else {
+			r.rules = append(r.rules, rules...)
+		}
+	}
+	if r == nil {
+		return self, nil
+	}
+
+	if self == parent {
+		// Not tracking this directory for symbolic link cycles, but its
+		// children still need its rules.
+		self = &ancestor{osPathname: osPathname, parent: parent}
+	}
+	self.ignore = r
+	return self, nil
+}
+
+// readFile returns the contents of the file with the specified relative name in
+// the directory specified by osPathname, open as dirfd.
+func (ws *walkState) readFile(dirfd int, osPathname, name string) ([]byte, error) {
+	if ws.reader != nil {
+		return ws.reader.readFile(path.Join(osPathname, name))
+	}
+	osFilename := filepath.Join(osPathname, filepath.FromSlash(name))
+	if dirfd < 0 {
+		return readFileAt(atFDCWD, osFilename, osFilename)
+	}
+	return readFileAt(dirfd, name, osFilename)
+}
+
+// maxIgnoreFileSize is the size of the largest ignore file Walk reads, the same
+// limit git(1) applies.
+const maxIgnoreFileSize = 100 << 20
+
+// ignoreFile is an open ignore file, either an *os.File or an io/fs.File.
+type ignoreFile interface {
+	io.Reader
+	Stat() (os.FileInfo, error)
+}
+
+// readIgnoreFile returns the contents of the open ignore file fh, whose
+// pathname is osPathname, or no contents when it is not a regular file, so
+// that a FIFO or device planted as an ignore file can neither block the walk
+// nor exhaust its memory. It returns an error rather than reading more than
+// maxIgnoreFileSize bytes.
+func readIgnoreFile(fh ignoreFile, osPathname string) ([]byte, error) {
+	fi, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(fh, maxIgnoreFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxIgnoreFileSize {
+		return nil, fmt.Errorf("cannot read ignore file larger than %d bytes: %s", maxIgnoreFileSize, osPathname)
+	}
+	return data, nil
+}
@@ -0,0 +1,206 @@
+package godirwalk
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreRules([]byte(strings.Join([]string{
+		"# comment",
+		"",
+		`\#literal`,
+		"*.o   ",
+		`trailing\ `,
+		"bin/",
+		"/root-only",
+		"doc/*.txt",
+		"logs/**",
+		"**/cache",
+		"[!a]x",
+		"!keep.o",
+		"[",
+	}, "\r\n")))
+
+	cases := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{"#literal", false, true},
+		{"# comment", false, false},
+		{"main.o", false, true},
+		{"sub/dir/main.o", false, true},
+		{"keep.o", false, false},
+		{"sub/keep.o", false, false},
+		{"trailing ", false, true},
+		{"trailing", false, false},
+		{"bin", true, true},
+		{"bin", false, false},
+		{"sub/bin", true, true},
+		{"root-only", false, true},
+		{"sub/root-only", false, false},
+		{"doc/notes.txt", false, true},
+		{"doc/sub/notes.txt", false, false},
+		{"sub/doc/notes.txt", false, false},
+		{"logs", true, false},
+		{"logs/today", false, true},
+		{"sub/cache", true, true},
+		{"bx", false, true},
+		{"ax", false, false},
+	}
+
+	r := &ignoreRules{rules: rules}
+	for _, c := range cases {
+		if got, want := r.ignores(filepath.FromSlash(c.name), c.isDir), c.want; got != want {
+			t.Errorf("%q: GOT: %v; WANT: %v", c.name, got, want)
+		}
+	}
+}
+
+func TestWalkGitIgnore(t *testing.T) {
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+	ensureError(t, err)
+	defer os.RemoveAll(testroot)
+
+	git, err := exec.LookPath("git")
+	if err == nil {
+		cmd := exec.Command(git, "init", "-q", testroot)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("cannot initialize repository: %s: %s", err, output)
+		}
+	}
+
+	files := map[string]string{
+		".git/info/exclude":   "*.log\n",
+		".gitignore":          "node_modules/\n/build\n*.tmp\n!keep.tmp\ndocs/**/*.pdf\n",
+		".ignore":             "a/c\n",
+		"a/.gitignore":        "!important.log\nsecret\n",
+		"a/b.tmp":             "",
+		"a/build/x":           "",
+		"a/c/secret":          "",
+		"a/c/y":               "",
+		"a/important.log":     "",
+		"a/keep.tmp":          "",
+		"a/node_modules/z":    "",
+		"a/secret":            "",
+		"build/out":           "",
+		"debug.log":           "",
+		"docs/a/b.pdf":        "",
+		"docs/c.pdf":          "",
+		"docs/readme.md":      "",
+		"keep.tmp":            "",
+		"main.go":             "",
+		"node_modules/x/y.js": "",
+		"x.tmp":               "",
+	}
+	for name, contents := range files {
+		osPathname := filepath.Join(testroot, filepath.FromSlash(name))
+		ensureError(t, os.MkdirAll(filepath.Dir(osPathname), os.ModePerm))
+		ensureError(t, ioutil.WriteFile(osPathname, []byte(contents), 0644))
+	}
+
+	// ignoreWalk returns the relative slash-separated pathnames of the regular
+	// files Walk visits.
+	ignoreWalk := func(t *testing.T, options *Options) []string {
+		t.Helper()
+		var actual []string
+		options.Callback = func(osPathname string, de *Dirent) error {
+			if de.IsRegular() {
+				actual = append(actual, filepath.ToSlash(osPathname[len(testroot)+1:]))
+			}
+			return nil
+		}
+		ensureError(t, Walk(testroot, options))
+		sort.Strings(actual)
+		return actual
+	}
+
+	expected := []string{
+		".gitignore",
+		".ignore",
+		"a/.gitignore",
+		"a/build/x",
+		"a/c/y",
+		"a/important.log",
+		"a/keep.tmp",
+		"docs/readme.md",
+		"keep.tmp",
+		"main.go",
+	}
+
+	t.Run("git ignore", func(t *testing.T) {
+		for _, order := range []Order{DepthFirst, BreadthFirst} {
+			actual := ignoreWalk(t, &Options{GitIgnore: true, Order: order})
+			if got, want := strings.Join(actual, "\n"), strings.Join(expected, "\n"); got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+		}
+	})
+
+	t.Run("same as git", func(t *testing.T) {
+		if git == "" {
+			t.Skip("git not found")
+		}
+		cmd := exec.Command(git, "ls-files", "--others", "--exclude-standard")
+		cmd.Dir = testroot
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+testroot)
+		output, err := cmd.Output()
+		ensureError(t, err)
+
+		actual := ignoreWalk(t, &Options{GitIgnore: true})
+		want := strings.TrimSpace(string(output))
+		if got := strings.Join(actual, "\n"); got != want {
+			t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+		}
+	})
+
+	t.Run("ignore file", func(t *testing.T) {
+		actual := ignoreWalk(t, &Options{GitIgnore: true, IgnoreFile: ".ignore"})
+
+		var want []string
+		for _, name := range expected {
+			if !strings.HasPrefix(name, "a/c/") {
+				want = append(want, name)
+			}
+		}
+		if got, want := strings.Join(actual, "\n"), strings.Join(want, "\n"); got != want {
+			t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+		}
+
+		// Without GitIgnore, only the ignore file is read.
+		var count int
+		for _, name := range ignoreWalk(t, &Options{IgnoreFile: ".ignore"}) {
+			if strings.HasPrefix(name, "a/c/") {
+				t.Errorf("GOT: %v; WANT: ignored", name)
+			}
+			if !strings.HasPrefix(name, ".git/") {
+				count++
+			}
+		}
+		if got, want := count, len(files)-3; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("walker", func(t *testing.T) {
+		w, err := NewWalker(testroot, &Options{GitIgnore: true, FollowSymbolicLinks: true})
+		ensureError(t, err)
+		var actual []string
+		for w.Next() {
+			if w.Dirent().IsRegular() {
+				actual = append(actual, filepath.ToSlash(w.Path()[len(testroot)+1:]))
+			}
+		}
+		ensureError(t, w.Close())
+		sort.Strings(actual)
+		if got, want := strings.Join(actual, "\n"), strings.Join(expected, "\n"); got != want {
+			t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+		}
+	})
+}
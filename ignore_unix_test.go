@@ -0,0 +1,60 @@
+//go:build !windows
+// +build !windows
+
+package godirwalk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWalkGitIgnoreNotRegular(t *testing.T) {
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+	ensureError(t, err)
+	defer os.RemoveAll(testroot)
+
+	ensureError(t, os.Mkdir(filepath.Join(testroot, "a"), os.ModePerm))
+	ensureError(t, ioutil.WriteFile(filepath.Join(testroot, "a", "x"), nil, 0644))
+	ensureError(t, ioutil.WriteFile(filepath.Join(testroot, "y"), nil, 0644))
+	ensureError(t, ioutil.WriteFile(filepath.Join(testroot, "patterns"), []byte("*\n"), 0644))
+
+	// Opening a FIFO for reading blocks until a writer opens it, and reading
+	// /dev/zero never ends, so neither may be read as an ignore file. Nor may
+	// a symbolic link, even to a regular file.
+	ensureError(t, syscall.Mkfifo(filepath.Join(testroot, ".gitignore"), 0644))
+	ensureError(t, syscall.Mkfifo(filepath.Join(testroot, "a", ".gitignore"), 0644))
+	ensureError(t, os.Symlink("/dev/zero", filepath.Join(testroot, ".ignore")))
+	ensureError(t, os.Symlink("../patterns", filepath.Join(testroot, "a", ".ignore")))
+
+	var actual []string
+	done := make(chan error, 1)
+	go func() {
+		done <- Walk(testroot, &Options{
+			GitIgnore:  true,
+			IgnoreFile: ".ignore",
+			Callback: func(osPathname string, de *Dirent) error {
+				if de.IsRegular() {
+					actual = append(actual, filepath.ToSlash(osPathname[len(testroot)+1:]))
+				}
+				return nil
+			},
+		})
+	}()
+	select {
+	case err := <-done:
+		ensureError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("walk blocked reading an ignore file")
+	}
+
+	sort.Strings(actual)
+	if got, want := strings.Join(actual, " "), "a/x patterns y"; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}
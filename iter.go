@@ -0,0 +1,74 @@
+//go:build go1.23
+// +build go1.23
+
+package godirwalk
+
+import "iter"
+
+// All returns an iterator over the file system nodes in the file tree rooted at
+// the specified directory, visiting the same nodes in the same order as Walk,
+// and yielding the OS pathname and the Dirent of each node.
+//
+//    for osPathname, de := range godirwalk.All(dirname, nil) {
+//        fmt.Printf("%s %s\n", de.ModeType(), osPathname)
+//    }
+//
+// The options may be nil, and are interpreted exactly like they are by
+// NewWalker. Breaking out of the loop stops the walk and closes every directory
+// it has open. Because an iterator cannot return an error, errors are sent to
+// the ErrorCallback function of the provided options, whose return value
+// determines whether the iteration skips the node that caused the error or
+// halts. When no ErrorCallback function is provided, the first error silently
+// ends the iteration. Programs that need to inspect that error, or to skip
+// directories, should use a Walker instead.
+func All(root string, opts *Options) iter.Seq2[string, *Dirent] {
+	return func(yield func(string, *Dirent) bool) {
+		w, err := NewWalker(root, opts)
+		if err != nil {
+			if opts != nil && opts.ErrorCallback != nil {
+				_ = opts.ErrorCallback(root, err)
+			}
+			return
+		}
+		defer w.Close()
+
+		for w.Next() {
+			if !yield(w.Path(), w.Dirent()) {
+				return
+			}
+		}
+	}
+}
+
+// Entries returns an iterator over the immediate descendants of the specified
+// directory, enumerated lazily by a Scanner in the order the operating system
+// provides them. When the directory cannot be read, or a directory entry
+// cannot be decoded, the iterator yields the error.
+//
+//    for de, err := range godirwalk.Entries(dirname) {
+//        if err != nil {
+//            return err
+//        }
+//        fmt.Printf("%s %s\n", de.ModeType(), de.Name())
+//    }
+//
+// Breaking out of the loop closes the directory.
+func Entries(dir string) iter.Seq2[*Dirent, error] {
+	return func(yield func(*Dirent, error) bool) {
+		scanner, err := NewScanner(dir)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer scanner.Close()
+
+		for scanner.Scan() {
+			if !yield(scanner.Dirent()) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
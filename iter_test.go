@@ -0,0 +1,91 @@
+//go:build go1.23
+// +build go1.23
+
+package godirwalk
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	t.Run("same order as walk", func(t *testing.T) {
+		osDirname := filepath.Join(scaffolingRoot, "d0")
+		expected := godirwalkWalk(t, osDirname)
+
+		var actual []string
+		for osPathname, de := range All(osDirname, nil) {
+			if de.Name() == "skip" {
+				continue
+			}
+			actual = append(actual, osPathname)
+		}
+
+		// Without SkipDir, All also visits the descendants of the skip
+		// directory, and the siblings following the skip file.
+		expected = append(expected,
+			filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip/f5"),
+		)
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("break", func(t *testing.T) {
+		var count int
+		for _, de := range All(filepath.Join(scaffolingRoot, "d0"), nil) {
+			count++
+			if de.Name() == "d1" {
+				break
+			}
+		}
+		if got, want := count, 3; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("error callback", func(t *testing.T) {
+		var errorCallbackVisited bool
+
+		for range All(filepath.Join(scaffolingRoot, "d0/symlinks"), &Options{
+			ErrorCallback: func(osPathname string, err error) ErrorAction {
+				if filepath.Base(osPathname) == "nothing" {
+					errorCallbackVisited = true
+				}
+				return SkipNode
+			},
+			FollowSymbolicLinks: true,
+		}) {
+		}
+
+		if got, want := errorCallbackVisited, true; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
+
+func TestEntries(t *testing.T) {
+	t.Run("collect names", func(t *testing.T) {
+		var actual []string
+		for de, err := range Entries(filepath.Join(scaffolingRoot, "d0")) {
+			ensureError(t, err)
+			actual = append(actual, de.Name())
+		}
+
+		expected := []string{maxName, "d1", "f1", "skips", "symlinks"}
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		var count int
+		for de, err := range Entries(filepath.Join(scaffolingRoot, "missing")) {
+			count++
+			if de != nil {
+				t.Errorf("GOT: %v; WANT: nil", de)
+			}
+			ensureError(t, err, "missing")
+		}
+		if got, want := count, 1; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package match
+
+import "unicode/utf8"
+
+// fnmatch reports whether name matches the shell pattern, like fnmatch(3)
+// without flags: "*" matches any string and "?" any character, both including
+// "/" and a leading ".", a bracket expression such as "[a-z]" or "[!0-9]"
+// matches one of its characters, "\" quotes the next character, and a "[" that
+// does not start a bracket expression matches itself.
+func fnmatch(pattern, name string) bool {
+	var px, nx int          // positions in pattern and name
+	starPx, starNx := -1, 0 // positions to resume from when a match fails after "*"
+
+	for px < len(pattern) || nx < len(name) {
+		if px < len(pattern) {
+			switch c := pattern[px]; c {
+			case '*':
+				// Try to match the remainder of the name first, and on failure
+				// let the star consume one more character.
+				starPx, starNx = px, nx
+				px++
+				continue
+			case '?':
+				if nx < len(name) {
+					_, width := utf8.DecodeRuneInString(name[nx:])
+					px, nx = px+1, nx+width
+					continue
+				}
+			case '[':
+				if nx < len(name) {
+					r, width := utf8.DecodeRuneInString(name[nx:])
+					if matched, length, ok := matchBracket(pattern[px:], r); ok {
+						if matched {
+							px, nx = px+length, nx+width
+							continue
+						}
+					} else if name[nx] == '[' {
+						px, nx = px+1, nx+1
+						continue
+					}
+				}
+			case '\\':
+				if px+1 < len(pattern) {
+					px++ // match the quoted character literally
+				}
+				fallthrough
+			default:
+				if nx < len(name) && name[nx] == pattern[px] {
+					px, nx = px+1, nx+1
+					continue
+				}
+			}
+		}
+		if starPx >= 0 && starNx < len(name) {
+			_, width := utf8.DecodeRuneInString(name[starNx:])
+			starNx += width
+			px, nx = starPx+1, starNx
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// matchBracket reports whether r matches the bracket expression at the start of
+// pattern, along with the length of that expression, or returns false for ok
+// when pattern does not start with a complete bracket expression.
+func matchBracket(pattern string, r rune) (matched bool, length int, ok bool) {
+	i := 1 // skip "["
+	negate := i < len(pattern) && (pattern[i] == '!' || pattern[i] == '^')
+	if negate {
+		i++
+	}
+
+	for first := true; ; first = false {
+		if i >= len(pattern) {
+			return false, 0, false
+		}
+		if pattern[i] == ']' && !first {
+			return matched != negate, i + 1, true
+		}
+		lo, width := bracketRune(pattern[i:])
+		i += width
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			hi, width = bracketRune(pattern[i+1:])
+			i += 1 + width
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+}
+
+// bracketRune returns the character at the start of s, which is part of a
+// bracket expression, along with its length, including a quoting "\".
+func bracketRune(s string) (rune, int) {
+	if s[0] == '\\' && len(s) > 1 {
+		r, width := utf8.DecodeRuneInString(s[1:])
+		return r, width + 1
+	}
+	return utf8.DecodeRuneInString(s)
+}
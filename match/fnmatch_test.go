@@ -0,0 +1,42 @@
+package match
+
+import "testing"
+
+func TestFnmatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", ".hidden", true},
+		{"*", "a/b", true},
+		{"a*b", "a/x/b", true},
+		{"a*b", "a/x/c", false},
+		{"*.go", "main.go", true},
+		{"*.go", "main.gox", false},
+		{"*a*a*", "banana", true},
+		{"?", "é", true},
+		{"??", "é", false},
+		{"[abc]", "b", true},
+		{"[a-c]x", "cx", true},
+		{"[!a-c]", "d", true},
+		{"[^a-c]", "a", false},
+		{"[]]", "]", true},
+		{"[!]]", "a", true},
+		{"[\\]]", "]", true},
+		{"[é]", "é", true},
+		{"[", "[", true},
+		{"[a", "[a", true},
+		{"\\*", "*", true},
+		{"\\*", "a", false},
+		{"a\\", "a\\", true},
+	}
+
+	for _, c := range cases {
+		if got := fnmatch(c.pattern, c.name); got != c.want {
+			t.Errorf("fnmatch(%q, %q): GOT: %v; WANT: %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
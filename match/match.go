@@ -0,0 +1,477 @@
+/*
+Package match provides composable predicates that select file system nodes
+while walking a directory tree with godirwalk, much like the tests of find(1).
+
+A Predicate is built from the provided tests, such as Name, Glob, Path,
+PathGlob, Type, Size, ModTime, Owner, Perm, and Empty, which combine with And,
+Or, and Not, and which Follow evaluates against the nodes symbolic links refer
+to rather than the symbolic links themselves. The Filter function turns a
+Predicate into a function suitable for the Filter field of godirwalk.Options:
+
+    name, err := match.Name("*.go")
+    if err != nil {
+        fatal("cannot compile pattern: %s", err)
+    }
+
+    err = godirwalk.Walk(dirname, &godirwalk.Options{
+        Filter: match.Filter(match.And(
+            match.Type(0), // regular files
+            name,
+            match.ModTime(time.Now().Add(-24*time.Hour), time.Time{}),
+        )),
+        Callback: func(osPathname string, de *godirwalk.Dirent) error {
+            fmt.Println(osPathname)
+            return nil
+        },
+    })
+
+Tests of the name, pathname, and type of a node are answered by the directory
+entry Walk already read, while the others require the file information of the
+node, or even reading a directory. And and Or evaluate their operands from the
+cheapest to the most expensive, and stop as soon as the result is known, so the
+file information of a node is only obtained from the Info method of its Dirent
+once the cheaper tests have passed. Because the Dirent remembers it, the file
+system is queried at most once no matter how many tests require it. Both are
+also obtained through the file system being walked, so predicates work the
+same with godirwalk.WalkFS.
+*/
+package match
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/karrick/godirwalk"
+)
+
+// Costs of evaluating predicates, used to evaluate the cheapest operands of And
+// and Or first.
+const (
+	costDirent = iota // answered by the directory entry
+	costPath          // answered by matching the pathname
+	costStat          // requires the file information of the node
+	costRead          // requires reading the directory of the node
+)
+
+// Predicate is a test of a file system node. Predicates are safe to use from
+// multiple goroutines, such as when walking with multiple Workers.
+type Predicate interface {
+	// cost returns how expensive the predicate is to evaluate.
+	cost() int
+
+	// match reports whether the node satisfies the predicate.
+	match(n *node) (bool, error)
+}
+
+// node is the file system node a predicate is evaluated against.
+type node struct {
+	osPathname string
+	de         *godirwalk.Dirent
+	follow     bool        // whether symbolic links are followed, within Follow
+	fi         os.FileInfo // file information when following symbolic links
+	err        error
+	statted    bool // whether fi and err are valid
+}
+
+// info returns the file information of the node from its Dirent. Unless
+// following symbolic links, it is remembered by the Dirent, which queries the
+// file system the first time it is needed. When following symbolic links, the
+// information of a symbolic link whose referent does not exist is that of the
+// symbolic link itself.
+func (n *node) info() (os.FileInfo, error) {
+	if !n.follow {
+		return n.de.Info()
+	}
+	if !n.statted {
+		n.fi, n.err = n.de.Stat()
+		if os.IsNotExist(n.err) && n.de.IsSymlink() {
+			n.fi, n.err = n.de.Info()
+		}
+		n.statted = true
+	}
+	return n.fi, n.err
+}
+
+// modeType returns the mode type bits of the node, which only requires its file
+// information when following a symbolic link.
+func (n *node) modeType() (os.FileMode, error) {
+	if !n.follow || !n.de.IsSymlink() {
+		return n.de.ModeType(), nil
+	}
+	fi, err := n.info()
+	if err != nil {
+		return 0, err
+	}
+	return fi.Mode() & os.ModeType, nil
+}
+
+// Filter returns a function that reports whether the file system node specified
+// by osPathname and de satisfies p, suitable for the Filter field of
+// godirwalk.Options.
+func Filter(p Predicate) func(osPathname string, de *godirwalk.Dirent) (bool, error) {
+	return func(osPathname string, de *godirwalk.Dirent) (bool, error) {
+		return p.match(&node{osPathname: osPathname, de: de})
+	}
+}
+
+// byCost returns a copy of predicates, sorted from the cheapest to the most
+// expensive to evaluate, and the cost of evaluating the most expensive one.
+func byCost(predicates []Predicate) ([]Predicate, int) {
+	sorted := make([]Predicate, len(predicates))
+	copy(sorted, predicates)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].cost() < sorted[j].cost() })
+	var c int
+	if l := len(sorted); l > 0 {
+		c = sorted[l-1].cost()
+	}
+	return sorted, c
+}
+
+type and struct {
+	predicates []Predicate
+	c          int
+}
+
+// And returns a Predicate satisfied by the nodes that satisfy every one of
+// predicates, and by every node when there are none.
+func And(predicates ...Predicate) Predicate {
+	sorted, c := byCost(predicates)
+	return &and{predicates: sorted, c: c}
+}
+
+func (p *and) cost() int { return p.c }
+
+func (p *and) match(n *node) (bool, error) {
+	for _, operand := range p.predicates {
+		if ok, err := operand.match(n); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+type or struct {
+	predicates []Predicate
+	c          int
+}
+
+// Or returns a Predicate satisfied by the nodes that satisfy at least one of
+// predicates, and by no node when there are none.
+func Or(predicates ...Predicate) Predicate {
+	sorted, c := byCost(predicates)
+	return &or{predicates: sorted, c: c}
+}
+
+func (p *or) cost() int { return p.c }
+
+func (p *or) match(n *node) (bool, error) {
+	for _, operand := range p.predicates {
+		if ok, err := operand.match(n); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+type not struct {
+	predicate Predicate
+}
+
+// Not returns a Predicate satisfied by the nodes that do not satisfy p.
+func Not(p Predicate) Predicate { return &not{predicate: p} }
+
+func (p *not) cost() int { return p.predicate.cost() }
+
+func (p *not) match(n *node) (bool, error) {
+	ok, err := p.predicate.match(n)
+	return !ok && err == nil, err
+}
+
+type follow struct {
+	predicate Predicate
+}
+
+// Follow returns a Predicate that evaluates p against the nodes symbolic links
+// refer to rather than the symbolic links themselves, like the tests of find(1)
+// with its -L option. A symbolic link whose referent does not exist is
+// evaluated as itself.
+func Follow(p Predicate) Predicate { return &follow{predicate: p} }
+
+func (p *follow) cost() int {
+	if c := p.predicate.cost(); c > costStat {
+		return c
+	}
+	return costStat
+}
+
+func (p *follow) match(n *node) (bool, error) {
+	if n.follow {
+		return p.predicate.match(n)
+	}
+	return p.predicate.match(&node{osPathname: n.osPathname, de: n.de, follow: true})
+}
+
+type name struct {
+	pattern string
+}
+
+// Name returns a Predicate satisfied by the nodes whose base names match
+// pattern, using the syntax of path.Match, like the -name test of find(1). It
+// returns an error when pattern is malformed.
+func Name(pattern string) (Predicate, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("cannot compile pattern: %q: %s", pattern, err)
+	}
+	return &name{pattern: pattern}, nil
+}
+
+func (p *name) cost() int { return costDirent }
+
+func (p *name) match(n *node) (bool, error) {
+	ok, _ := path.Match(p.pattern, n.de.Name()) // pattern validated by Name
+	return ok, nil
+}
+
+type glob struct {
+	pattern string
+	fold    bool // whether to ignore case
+	whole   bool // whether to match the pathname rather than the base name
+}
+
+// Glob returns a Predicate satisfied by the nodes whose base names match
+// pattern, using the syntax of fnmatch(3), like the -name test of find(1), or
+// like its -iname test when fold is true, ignoring case. Unlike with Name, a "["
+// that does not start a bracket expression matches itself, so every pattern is
+// valid, although character classes such as "[:alpha:]" are not supported.
+func Glob(pattern string, fold bool) Predicate { return newGlob(pattern, fold, false) }
+
+// PathGlob returns a Predicate satisfied by the nodes whose pathnames, which
+// include the pathname Walk was invoked with as a prefix, match pattern, using
+// the syntax of fnmatch(3), like the -path test of find(1), or like its -ipath
+// test when fold is true, ignoring case. Both "*" and "?" match path
+// separators.
+func PathGlob(pattern string, fold bool) Predicate { return newGlob(pattern, fold, true) }
+
+func newGlob(pattern string, fold, whole bool) Predicate {
+	if fold {
+		pattern = strings.ToLower(pattern)
+	}
+	return &glob{pattern: pattern, fold: fold, whole: whole}
+}
+
+func (p *glob) cost() int {
+	if p.whole {
+		return costPath
+	}
+	return costDirent
+}
+
+func (p *glob) match(n *node) (bool, error) {
+	s := n.de.Name()
+	if p.whole {
+		s = n.osPathname
+	}
+	if p.fold {
+		s = strings.ToLower(s)
+	}
+	return fnmatch(p.pattern, s), nil
+}
+
+type pathRegexp struct {
+	re *regexp.Regexp
+}
+
+// Path returns a Predicate satisfied by the nodes whose pathnames, which
+// include the pathname Walk was invoked with as a prefix, contain a match of
+// re. Anchor re with "^" and "$" to match entire pathnames, like the -regex
+// test of find(1).
+func Path(re *regexp.Regexp) Predicate { return &pathRegexp{re: re} }
+
+func (p *pathRegexp) cost() int { return costPath }
+
+func (p *pathRegexp) match(n *node) (bool, error) {
+	return p.re.MatchString(n.osPathname), nil
+}
+
+type modeType struct {
+	types []os.FileMode
+}
+
+// Type returns a Predicate satisfied by the nodes whose mode type bits equal
+// one of types, like the -type test of find(1), such as os.ModeDir for
+// directories, os.ModeSymlink for symbolic links, and 0 for regular files.
+// Symbolic links are not followed, unless within Follow.
+func Type(types ...os.FileMode) Predicate {
+	masked := make([]os.FileMode, len(types))
+	for i, t := range types {
+		masked[i] = t & os.ModeType
+	}
+	return &modeType{types: masked}
+}
+
+func (p *modeType) cost() int { return costDirent }
+
+func (p *modeType) match(n *node) (bool, error) {
+	mt, err := n.modeType()
+	if err != nil {
+		return false, err
+	}
+	for _, t := range p.types {
+		if mt == t {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type size struct {
+	min, max int64
+}
+
+// Size returns a Predicate satisfied by the nodes whose sizes in bytes are at
+// least min and at most max, like the -size test of find(1). A negative max
+// means there is no upper bound.
+func Size(min, max int64) Predicate { return &size{min: min, max: max} }
+
+func (p *size) cost() int { return costStat }
+
+func (p *size) match(n *node) (bool, error) {
+	fi, err := n.info()
+	if err != nil {
+		return false, err
+	}
+	s := fi.Size()
+	return s >= p.min && (p.max < 0 || s <= p.max), nil
+}
+
+// timeRange is a predicate satisfied by the nodes whose times, returned by
+// which, are within a range.
+type timeRange struct {
+	after, before time.Time
+	what          string // name of the time, for error messages
+	which         func(os.FileInfo) (time.Time, bool)
+}
+
+func (p *timeRange) cost() int { return costStat }
+
+func (p *timeRange) match(n *node) (bool, error) {
+	fi, err := n.info()
+	if err != nil {
+		return false, err
+	}
+	t, ok := p.which(fi)
+	if !ok {
+		return false, fmt.Errorf("cannot get %s of %q on %s", p.what, n.osPathname, runtime.GOOS)
+	}
+	return (p.after.IsZero() || !t.Before(p.after)) && (p.before.IsZero() || t.Before(p.before)), nil
+}
+
+// ModTime returns a Predicate satisfied by the nodes last modified at or after
+// after, and before before, like the -mtime and -newer tests of find(1). A
+// zero time means there is no bound on that side.
+func ModTime(after, before time.Time) Predicate {
+	return &timeRange{after: after, before: before, what: "modification time", which: modTime}
+}
+
+// AccessTime returns a Predicate satisfied by the nodes last accessed at or
+// after after, and before before, like the -atime test of find(1). A zero time
+// means there is no bound on that side. The Predicate returns an error on
+// operating systems that do not record access times.
+func AccessTime(after, before time.Time) Predicate {
+	return &timeRange{after: after, before: before, what: "access time", which: accessTime}
+}
+
+// ChangeTime returns a Predicate satisfied by the nodes whose status last
+// changed at or after after, and before before, like the -ctime test of
+// find(1). A zero time means there is no bound on that side. The Predicate
+// returns an error on operating systems that do not record change times, such
+// as Windows.
+func ChangeTime(after, before time.Time) Predicate {
+	return &timeRange{after: after, before: before, what: "change time", which: changeTime}
+}
+
+func modTime(fi os.FileInfo) (time.Time, bool) { return fi.ModTime(), true }
+
+type owner struct {
+	uid int
+}
+
+// Owner returns a Predicate satisfied by the nodes owned by the user whose
+// numeric ID is uid, like the -uid test of find(1). The Predicate returns an
+// error on operating systems that do not record numeric owners, such as
+// Windows.
+func Owner(uid int) Predicate { return &owner{uid: uid} }
+
+func (p *owner) cost() int { return costStat }
+
+func (p *owner) match(n *node) (bool, error) {
+	fi, err := n.info()
+	if err != nil {
+		return false, err
+	}
+	uid, ok := ownerID(fi)
+	if !ok {
+		return false, fmt.Errorf("cannot get owner of %q on %s", n.osPathname, runtime.GOOS)
+	}
+	return uid == p.uid, nil
+}
+
+// permBits are the mode bits tested by Perm.
+const permBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+type perm struct {
+	bits os.FileMode
+}
+
+// Perm returns a Predicate satisfied by the nodes whose permission bits include
+// every one of bits, like the -perm test of find(1) when its mode starts with
+// "-". Besides the permission bits, bits may include os.ModeSetuid,
+// os.ModeSetgid, and os.ModeSticky, and its other bits are ignored.
+func Perm(bits os.FileMode) Predicate { return &perm{bits: bits & permBits} }
+
+func (p *perm) cost() int { return costStat }
+
+func (p *perm) match(n *node) (bool, error) {
+	fi, err := n.info()
+	if err != nil {
+		return false, err
+	}
+	return fi.Mode()&p.bits == p.bits, nil
+}
+
+type empty struct{}
+
+// Empty returns a Predicate satisfied by the empty regular files and empty
+// directories, like the -empty test of find(1).
+func Empty() Predicate { return empty{} }
+
+func (empty) cost() int { return costRead }
+
+func (empty) match(n *node) (bool, error) {
+	mt, err := n.modeType()
+	if err != nil {
+		return false, err
+	}
+	switch mt {
+	case 0:
+		fi, err := n.info()
+		if err != nil {
+			return false, err
+		}
+		return fi.Size() == 0, nil
+	case os.ModeDir:
+		names, err := n.de.ReadDirnames(nil)
+		if err != nil {
+			return false, err
+		}
+		return len(names) == 0, nil
+	default:
+		return false, nil
+	}
+}
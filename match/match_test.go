@@ -0,0 +1,230 @@
+package match
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karrick/godirwalk"
+)
+
+// matchTree creates a directory tree for testing predicates, and returns its
+// root, which the caller must remove.
+func matchTree(t *testing.T) string {
+	t.Helper()
+
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-match-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []struct {
+		name     string
+		contents string
+		mode     os.FileMode
+	}{
+		{"a.go", "package a\n", 0644},
+		{"b.txt", "", 0644},
+		{"run.sh", "#!/bin/sh\n", 0755},
+		{"sub/c.go", strings.Repeat("/", 100), 0644},
+		{"sub/old.go", "package sub\n", 0644},
+	}
+	for _, f := range files {
+		osPathname := filepath.Join(testroot, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(osPathname), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(osPathname, []byte(f.contents), f.mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(osPathname, f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(testroot, "sub/old.go"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(testroot, "empty"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	return testroot
+}
+
+// matchWalk returns the sorted slash-separated pathnames, relative to
+// testroot, of the nodes that satisfy p.
+func matchWalk(t *testing.T, testroot string, p Predicate) []string {
+	t.Helper()
+	var actual []string
+	err := godirwalk.Walk(testroot, &godirwalk.Options{
+		Filter: Filter(p),
+		Callback: func(osPathname string, _ *godirwalk.Dirent) error {
+			rel, err := filepath.Rel(testroot, osPathname)
+			if err != nil {
+				return err
+			}
+			actual = append(actual, filepath.ToSlash(rel))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(actual)
+	return actual
+}
+
+// mustName returns the Predicate returned by Name, or fails the test.
+func mustName(t *testing.T, pattern string) Predicate {
+	t.Helper()
+	p, err := Name(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestPredicates(t *testing.T) {
+	testroot := matchTree(t)
+	defer os.RemoveAll(testroot)
+
+	old := time.Date(2001, time.January, 1, 0, 0, 0, 0, time.UTC)
+	files := Type(0)
+
+	cases := []struct {
+		name      string
+		predicate Predicate
+		expected  []string
+	}{
+		{"name", mustName(t, "*.go"), []string{"a.go", "sub/c.go", "sub/old.go"}},
+		{"glob", Glob("*.GO", true), []string{"a.go", "sub/c.go", "sub/old.go"}},
+		{"glob case", Glob("*.GO", false), nil},
+		{"path", Path(regexp.MustCompile(`sub[/\\]`)), []string{"sub/c.go", "sub/old.go"}},
+		{"path glob", PathGlob("*SUB?C.GO", true), []string{"sub/c.go"}},
+		{"type", Type(os.ModeDir), []string{".", "empty", "sub"}},
+		{"size", And(files, Size(1, 50)), []string{"a.go", "run.sh", "sub/old.go"}},
+		{"size unbounded", And(files, Size(100, -1)), []string{"sub/c.go"}},
+		{"mod time", ModTime(time.Time{}, old), []string{"sub/old.go"}},
+		{"change time", And(files, ChangeTime(time.Time{}, old)), nil},
+		{"perm", And(files, Perm(0100)), []string{"run.sh"}},
+		{"empty", Empty(), []string{"b.txt", "empty"}},
+		{"and", And(mustName(t, "*.go"), ModTime(old, time.Time{})), []string{"a.go", "sub/c.go"}},
+		{"or", Or(mustName(t, "*.sh"), Empty()), []string{"b.txt", "empty", "run.sh"}},
+		{"not", And(files, Not(mustName(t, "*.go"))), []string{"b.txt", "run.sh"}},
+		{"and nothing", And(), []string{".", "a.go", "b.txt", "empty", "run.sh", "sub", "sub/c.go", "sub/old.go"}},
+		{"or nothing", Or(), nil},
+	}
+
+	if runtime.GOOS != "windows" {
+		cases = append(cases, []struct {
+			name      string
+			predicate Predicate
+			expected  []string
+		}{
+			{"access time", AccessTime(time.Time{}, old), []string{"sub/old.go"}},
+			{"owner", And(files, Owner(os.Getuid())), []string{"a.go", "b.txt", "run.sh", "sub/c.go", "sub/old.go"}},
+			{"other owner", Owner(os.Getuid() + 1), nil},
+		}...)
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := matchWalk(t, testroot, c.predicate)
+			if got, want := strings.Join(actual, "\n"), strings.Join(c.expected, "\n"); got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestFollow(t *testing.T) {
+	testroot := matchTree(t)
+	defer os.RemoveAll(testroot)
+
+	symlinks := []struct{ name, target string }{
+		{"broken", "nowhere"},
+		{"link-to-c", filepath.Join("sub", "c.go")},
+		{"link-to-sub", "sub"},
+	}
+	for _, s := range symlinks {
+		if err := os.Symlink(s.target, filepath.Join(testroot, s.name)); err != nil {
+			t.Skipf("cannot create symbolic link: %s", err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		predicate Predicate
+		expected  []string
+	}{
+		{"type", Type(os.ModeDir), []string{".", "empty", "sub"}},
+		{"follow type", Follow(Type(os.ModeDir)), []string{".", "empty", "link-to-sub", "sub"}},
+		{"follow broken", Follow(Type(os.ModeSymlink)), []string{"broken"}},
+		{"follow size", Follow(And(Type(0), Size(100, -1))), []string{"link-to-c", "sub/c.go"}},
+		{"follow empty", And(Not(mustName(t, "empty")), Follow(Empty())), []string{"b.txt"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := matchWalk(t, testroot, c.predicate)
+			if got, want := strings.Join(actual, "\n"), strings.Join(c.expected, "\n"); got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestNameMalformed(t *testing.T) {
+	if _, err := Name("[z"); err == nil || !strings.Contains(err.Error(), "cannot compile pattern") {
+		t.Errorf("GOT: %v; WANT: cannot compile pattern", err)
+	}
+}
+
+func TestPredicatesStatLast(t *testing.T) {
+	testroot := matchTree(t)
+	defer os.RemoveAll(testroot)
+
+	// Once the node is removed, querying its file information fails, so these
+	// predicates only succeed when cheaper operands are evaluated first. Unlike
+	// NewDirent, ReadDirents does not obtain the file information of the node.
+	osPathname := filepath.Join(testroot, "a.go")
+	children, err := godirwalk.ReadDirents(testroot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var de *godirwalk.Dirent
+	for _, child := range children {
+		if child.Name() == "a.go" {
+			de = child
+		}
+	}
+	if de == nil {
+		t.Fatalf("GOT: nil; WANT: a.go")
+	}
+	if err = os.Remove(osPathname); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := Filter(And(Size(0, -1), mustName(t, "*.txt")))(osPathname, de)
+	if ok || err != nil {
+		t.Errorf("GOT: %v, %v; WANT: false, nil", ok, err)
+	}
+
+	ok, err = Filter(Or(Empty(), Not(Type(os.ModeDir))))(osPathname, de)
+	if !ok || err != nil {
+		t.Errorf("GOT: %v, %v; WANT: true, nil", ok, err)
+	}
+
+	ok, err = Filter(And(Size(0, -1), mustName(t, "*.go")))(osPathname, de)
+	if ok || !os.IsNotExist(err) {
+		t.Errorf("GOT: %v, %v; WANT: false, not exist error", ok, err)
+	}
+}
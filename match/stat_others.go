@@ -0,0 +1,21 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package match
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns false, because this operating system does not provide the
+// access time of a file through the standard library.
+func accessTime(os.FileInfo) (time.Time, bool) { return time.Time{}, false }
+
+// changeTime returns false, because this operating system does not provide the
+// change time of a file through the standard library.
+func changeTime(os.FileInfo) (time.Time, bool) { return time.Time{}, false }
+
+// ownerID returns false, because this operating system does not provide the
+// owner of a file through the standard library.
+func ownerID(os.FileInfo) (int, bool) { return 0, false }
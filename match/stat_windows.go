@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package match
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+func accessTime(fi os.FileInfo) (time.Time, bool) {
+	fad, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(0, fad.LastAccessTime.Nanoseconds()), true
+}
+
+// changeTime returns false, because Windows does not record the time the
+// status of a file last changed.
+func changeTime(os.FileInfo) (time.Time, bool) { return time.Time{}, false }
+
+// ownerID returns false, because Windows identifies owners by security
+// identifiers rather than numeric IDs.
+func ownerID(os.FileInfo) (int, bool) { return 0, false }
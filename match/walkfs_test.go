@@ -0,0 +1,55 @@
+//go:build go1.16
+// +build go1.16
+
+package match
+
+import (
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/karrick/godirwalk"
+)
+
+func TestPredicatesWalkFS(t *testing.T) {
+	// None of these nodes exist on the disk, so predicates only succeed when
+	// they query the file system being walked.
+	fsys := fstest.MapFS{
+		"a.go":      {Data: []byte("package a\n")},
+		"b.txt":     {},
+		"sub/c.go":  {Data: []byte(strings.Repeat("/", 100))},
+		"sub/empty": {Mode: fs.ModeDir},
+	}
+
+	cases := []struct {
+		name      string
+		predicate Predicate
+		expected  []string
+	}{
+		{"size", And(Type(0), Size(1, 50)), []string{"a.go"}},
+		{"size unbounded", Size(100, -1), []string{"sub/c.go"}},
+		{"empty", Empty(), []string{"b.txt", "sub/empty"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var actual []string
+			err := godirwalk.WalkFS(fsys, ".", &godirwalk.Options{
+				Filter: Filter(c.predicate),
+				Callback: func(pathname string, _ *godirwalk.Dirent) error {
+					actual = append(actual, pathname)
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(actual)
+			if got, want := strings.Join(actual, "\n"), strings.Join(c.expected, "\n"); got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+		})
+	}
+}
@@ -2,19 +2,20 @@ package godirwalk
 
 import (
 	"os"
+	"path/filepath"
 )
 
-// modeType returns the mode type of the file system entry identified by
-// osPathname by calling os.LStat function, to intentionally not follow symbolic
-// links.
+// modeType returns the mode type of the file system entry with the specified
+// name in the directory open as dirfd, whose pathname is osDirname, by calling
+// statAt without following symbolic links.
 //
-// Even though os.LStat provides all file mode bits, we want to ensure same
+// Even though statAt provides all file mode bits, we want to ensure same
 // values returned to caller regardless of whether we obtained file mode bits
 // from syscall or stat call.  Therefore mask out the additional file mode bits
 // that are provided by stat but not by the syscall, so users can rely on their
 // values.
-func modeType(osPathname string) (os.FileMode, error) {
-	fi, err := os.Lstat(osPathname)
+func modeType(dirfd int, osDirname, osBasename string) (os.FileMode, error) {
+	fi, err := statAt(dirfd, osBasename, filepath.Join(osDirname, osBasename), false)
 	if err == nil {
 		return fi.Mode() & os.ModeType, nil
 	}
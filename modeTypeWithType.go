@@ -4,7 +4,6 @@ package godirwalk
 
 import (
 	"os"
-	"path/filepath"
 	"syscall"
 )
 
@@ -12,8 +11,8 @@ import (
 // of OS, to a constant defined by Go, assumed by this project to be stable.
 //
 // When the syscall constant is not recognized, this function falls back to a
-// Stat on the file system.
-func modeTypeFromDirent(de *syscall.Dirent, osDirname, osBasename string) (os.FileMode, error) {
+// Stat on the file system, relative to the directory open as dirfd.
+func modeTypeFromDirent(de *syscall.Dirent, dirfd int, osDirname, osBasename string) (os.FileMode, error) {
 	switch de.Type {
 	case syscall.DT_REG:
 		return 0, nil
@@ -32,6 +31,6 @@ func modeTypeFromDirent(de *syscall.Dirent, osDirname, osBasename string) (os.Fi
 	default:
 		// If syscall returned unknown type (e.g., DT_UNKNOWN, DT_WHT), then
 		// resolve actual mode by reading file information.
-		return modeType(filepath.Join(osDirname, osBasename))
+		return modeType(dirfd, osDirname, osBasename)
 	}
 }
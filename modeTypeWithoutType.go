@@ -4,7 +4,6 @@ package godirwalk
 
 import (
 	"os"
-	"path/filepath"
 	"syscall"
 )
 
@@ -12,7 +11,8 @@ import (
 // of OS, to a constant defined by Go, assumed by this project to be stable.
 //
 // Because some operating system syscall.Dirent structures do not include a Type
-// field, fall back on Stat of the file system.
-func modeTypeFromDirent(_ *syscall.Dirent, osDirname, osBasename string) (os.FileMode, error) {
-	return modeType(filepath.Join(osDirname, osBasename))
+// field, fall back on Stat of the file system, relative to the directory open as
+// dirfd.
+func modeTypeFromDirent(_ *syscall.Dirent, dirfd int, osDirname, osBasename string) (os.FileMode, error) {
+	return modeType(dirfd, osDirname, osBasename)
 }
@@ -0,0 +1,81 @@
+//go:build go1.16 && !windows
+// +build go1.16,!windows
+
+package godirwalk
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalkOneFileSystem(t *testing.T) {
+	t.Run("does not descend into other devices", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"mnt":        &fstest.MapFile{Mode: fs.ModeDir, Sys: &syscall.Stat_t{Dev: 2}},
+			"mnt/f1":     &fstest.MapFile{Data: []byte("f1")},
+			"same":       &fstest.MapFile{Mode: fs.ModeDir},
+			"same/f2":    &fstest.MapFile{Data: []byte("f2")},
+			"same/f3":    &fstest.MapFile{Data: []byte("f3")},
+			"top-level1": &fstest.MapFile{Data: []byte("top-level1")},
+		}
+
+		var actual, post []string
+
+		err := WalkFS(fsys, ".", &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, osPathname)
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				post = append(post, osPathname)
+				return nil
+			},
+			OneFileSystem: true,
+		})
+		ensureError(t, err)
+
+		expected := []string{".", "mnt", "same", "same/f2", "same/f3", "top-level1"}
+		ensureStringSlicesMatch(t, actual, expected)
+
+		expected = []string{"same", "."}
+		ensureStringSlicesMatch(t, post, expected)
+	})
+
+	t.Run("proc", func(t *testing.T) {
+		root, err := os.Stat("/")
+		ensureError(t, err)
+		proc, err := os.Stat("/proc")
+		if err != nil || deviceFromFileInfo(proc) == deviceFromFileInfo(root) {
+			t.Skip("/proc is not a separate file system")
+		}
+
+		var sawProc bool
+
+		err = Walk("/", &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				if osPathname == "/proc" {
+					sawProc = true
+				} else if strings.HasPrefix(osPathname, "/proc"+string(filepath.Separator)) {
+					t.Errorf("GOT: %v; WANT: no descendants of /proc", osPathname)
+				}
+				return nil
+			},
+			ErrorCallback: func(string, error) ErrorAction {
+				return SkipNode
+			},
+			MaxDepth:      2,
+			OneFileSystem: true,
+			Unsorted:      true,
+		})
+		ensureError(t, err)
+
+		if got, want := sawProc, true; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
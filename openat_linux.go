@@ -0,0 +1,150 @@
+//go:build linux
+// +build linux
+
+package godirwalk
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// atFDCWD is the directory descriptor that causes openDirAt to open a pathname
+// relative to the current working directory. It is the value of AT_FDCWD,
+// which package syscall does not export.
+const atFDCWD = -0x64
+
+// keepDirOpen is true because children are opened relative to the directory
+// containing them, which therefore remains open while they are walked.
+const keepDirOpen = true
+
+// openDirAt opens the directory with the specified name relative to the
+// directory open as dirfd, rather than by its full pathname, so that a
+// concurrent rename of a directory above it, or the replacement of such a
+// directory with a symbolic link, cannot redirect the walk outside of the file
+// system hierarchy being walked. Unless follow is true, it refuses to open a
+// symbolic link. The returned file is named osPathname.
+func openDirAt(dirfd int, name, osPathname string, follow bool) (*os.File, error) {
+	flags := syscall.O_RDONLY | syscall.O_DIRECTORY | syscall.O_CLOEXEC
+	if !follow {
+		flags |= syscall.O_NOFOLLOW
+	}
+	for {
+		fd, err := syscall.Openat(dirfd, name, flags, 0)
+		if err == nil {
+			return os.NewFile(uintptr(fd), osPathname), nil
+		}
+		if err != syscall.EINTR {
+			// Report the same error os.Open would have, so callers cannot
+			// tell how the directory was opened.
+			return nil, &os.PathError{Op: "open", Path: osPathname, Err: err}
+		}
+	}
+}
+
+// readFileAt returns the contents of the ignore file with the specified name
+// relative to the directory open as dirfd, rather than by its full pathname,
+// which is osPathname. It returns no contents when the file is a symbolic link,
+// which it does not follow, or is not a regular file, which it opens without
+// blocking so that it is never stuck opening a FIFO.
+func readFileAt(dirfd int, name, osPathname string) ([]byte, error) {
+	for {
+		fd, err := syscall.Openat(dirfd, name, syscall.O_RDONLY|syscall.O_CLOEXEC|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err == syscall.ELOOP {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: osPathname, Err: err}
+		}
+		fh := os.NewFile(uintptr(fd), osPathname)
+		data, err := readIgnoreFile(fh, osPathname)
+		_ = fh.Close()
+		return data, err
+	}
+}
+
+// oPATH is the value of O_PATH, which package syscall does not export on every
+// architecture.
+const oPATH = 0x200000
+
+// Flags of the resolve field of the open_how structure provided to openat2(2),
+// which package syscall does not define.
+const (
+	resolveNoMagiclinks = 0x02
+	resolveBeneath      = 0x08
+)
+
+// openHow is the open_how structure provided to openat2(2).
+type openHow struct {
+	flags, mode, resolve uint64
+}
+
+func openat2(dirfd int, name string, flags int, resolve uint64) (int, error) {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return -1, err
+	}
+	how := openHow{flags: uint64(flags), resolve: resolve}
+	fd, _, errno := syscall.Syscall6(sysOpenat2, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// openRootBeneath returns a descriptor for the root of a walk confined to it,
+// relative to which openDirBeneath may open its descendants, or -1 when the
+// kernel does not provide openat2(2), which is the case before Linux 5.6 or
+// when a seccomp filter forbids it.
+func openRootBeneath(osPathname string) int {
+	fd, err := openat2(atFDCWD, osPathname, oPATH|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1
+	}
+	return fd
+}
+
+// openDirBeneath opens the directory with the specified name relative to the
+// directory open as dirfd, like openDirAt, but the kernel refuses to resolve
+// the name to anything outside of that directory, whether by way of a symbolic
+// link, a ".." component, or a magic link such as those in /proc, in which case
+// it returns an error for which isEscape returns true.
+func openDirBeneath(dirfd int, name, osPathname string, follow bool) (*os.File, error) {
+	flags := syscall.O_RDONLY | syscall.O_DIRECTORY | syscall.O_CLOEXEC | syscall.O_LARGEFILE
+	if !follow {
+		flags |= syscall.O_NOFOLLOW
+	}
+	for {
+		fd, err := openat2(dirfd, name, flags, resolveBeneath|resolveNoMagiclinks)
+		if err == nil {
+			return os.NewFile(uintptr(fd), osPathname), nil
+		}
+		// EAGAIN means a concurrent rename may have affected the resolution.
+		if err != syscall.EINTR && err != syscall.EAGAIN {
+			return nil, &os.PathError{Op: "open", Path: osPathname, Err: err}
+		}
+	}
+}
+
+// isEscape returns true when err was returned by openDirBeneath because the
+// name would resolve to something outside of the directory.
+func isEscape(err error) bool {
+	pe, ok := err.(*os.PathError)
+	return ok && pe.Err == syscall.EXDEV
+}
+
+// dupDirfd returns a duplicate of the directory descriptor, which remains open
+// after the original is closed, until it is released with closeDirfd.
+func dupDirfd(dirfd int) (int, error) {
+	fd, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(dirfd), syscall.F_DUPFD_CLOEXEC, 0)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// closeDirfd releases a directory descriptor returned by dupDirfd.
+func closeDirfd(dirfd int) { _ = syscall.Close(dirfd) }
@@ -0,0 +1,238 @@
+package godirwalk
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestWalkOpenat(t *testing.T) {
+	// Build the hierarchy to walk, along with another hierarchy outside of it,
+	// to which the callback will attempt to redirect the walk.
+	setup := func(t *testing.T) (string, func()) {
+		t.Helper()
+		testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+		ensureError(t, err)
+		for _, name := range []string{"root/a/b/c/f1", "outside/b/c/evil", "outside/evil"} {
+			osPathname := filepath.Join(testroot, filepath.FromSlash(name))
+			ensureError(t, os.MkdirAll(filepath.Dir(osPathname), os.ModePerm))
+			ensureError(t, ioutil.WriteFile(osPathname, []byte(name), os.ModePerm))
+		}
+		return testroot, func() { _ = os.RemoveAll(testroot) }
+	}
+
+	for _, unsorted := range []bool{false, true} {
+		name := "sorted"
+		if unsorted {
+			name = "unsorted"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Run("directory replaced by symlink", func(t *testing.T) {
+				testroot, cleanup := setup(t)
+				defer cleanup()
+
+				osDirname := filepath.Join(testroot, "root")
+				var actual, errored []string
+
+				err := Walk(osDirname, &Options{
+					Callback: func(osPathname string, de *Dirent) error {
+						actual = append(actual, osPathname)
+						if de.Name() == "a" {
+							ensureError(t, os.Rename(osPathname, filepath.Join(osDirname, "moved")))
+							ensureError(t, os.Symlink(filepath.Join(testroot, "outside"), osPathname))
+						}
+						return nil
+					},
+					ErrorCallback: func(osPathname string, _ error) ErrorAction {
+						errored = append(errored, osPathname)
+						return SkipNode
+					},
+					Unsorted: unsorted,
+				})
+				ensureError(t, err)
+
+				ensureStringSlicesMatch(t, actual, []string{osDirname, filepath.Join(osDirname, "a")})
+				ensureStringSlicesMatch(t, errored, []string{filepath.Join(osDirname, "a")})
+			})
+
+			t.Run("ancestor replaced by symlink", func(t *testing.T) {
+				testroot, cleanup := setup(t)
+				defer cleanup()
+
+				osDirname := filepath.Join(testroot, "root")
+				var actual []string
+
+				err := Walk(osDirname, &Options{
+					Callback: func(osPathname string, de *Dirent) error {
+						actual = append(actual, osPathname)
+						if de.Name() == "b" {
+							a := filepath.Dir(osPathname)
+							ensureError(t, os.Rename(a, filepath.Join(osDirname, "moved")))
+							ensureError(t, os.Symlink(filepath.Join(testroot, "outside"), a))
+						}
+						return nil
+					},
+					Unsorted: unsorted,
+				})
+				ensureError(t, err)
+
+				// The pathnames still name the directories by the pathnames they
+				// had when found, but their contents are those of the hierarchy
+				// being walked.
+				expected := []string{
+					osDirname,
+					filepath.Join(osDirname, "a"),
+					filepath.Join(osDirname, "a/b"),
+					filepath.Join(osDirname, "a/b/c"),
+					filepath.Join(osDirname, "a/b/c/f1"),
+				}
+				ensureStringSlicesMatch(t, actual, expected)
+			})
+		})
+	}
+}
+
+func TestWalkLongPathnames(t *testing.T) {
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+	ensureError(t, err)
+	defer os.RemoveAll(testroot)
+
+	// Create a hierarchy whose deepest pathname is longer than PATH_MAX, which
+	// requires creating each directory relative to its parent.
+	const levels = 25
+	name := strings.Repeat("d", 200)
+
+	fd, err := syscall.Open(testroot, syscall.O_RDONLY|syscall.O_DIRECTORY, 0)
+	ensureError(t, err)
+	for i := 0; i < levels; i++ {
+		ensureError(t, syscall.Mkdirat(fd, name, 0755))
+		child, err := syscall.Openat(fd, name, syscall.O_RDONLY|syscall.O_DIRECTORY, 0)
+		ensureError(t, err)
+		ensureError(t, syscall.Close(fd))
+		fd = child
+	}
+	leaf, err := syscall.Openat(fd, "leaf", syscall.O_CREAT|syscall.O_WRONLY, 0644)
+	ensureError(t, err)
+	ensureError(t, syscall.Close(leaf))
+	ensureError(t, syscall.Close(fd))
+
+	expected := filepath.Join(testroot, strings.Repeat(name+string(filepath.Separator), levels)+"leaf")
+	if got, want := len(expected), syscall.PathMax; got <= want {
+		t.Fatalf("GOT: %v; WANT: > %v", got, want)
+	}
+
+	for _, options := range []Options{
+		{},
+		{Unsorted: true},
+		{FollowSymbolicLinks: true, OneFileSystem: true},
+	} {
+		var count int
+		var last string
+
+		options.Callback = func(osPathname string, _ *Dirent) error {
+			count++
+			last = osPathname
+			return nil
+		}
+		ensureError(t, Walk(testroot, &options))
+
+		if got, want := count, levels+2; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := last, expected; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	}
+
+	t.Run("walker", func(t *testing.T) {
+		actual := walkerWalk(t, testroot, nil)
+		if got, want := len(actual), levels+2; got != want {
+			t.Fatalf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := actual[len(actual)-1], expected; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
+
+func TestWalkConfineToRoot(t *testing.T) {
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+	ensureError(t, err)
+	defer os.RemoveAll(testroot)
+
+	for _, name := range []string{"root/a/f1", "root/b/f2", "outside/evil"} {
+		osPathname := filepath.Join(testroot, filepath.FromSlash(name))
+		ensureError(t, os.MkdirAll(filepath.Dir(osPathname), os.ModePerm))
+		ensureError(t, ioutil.WriteFile(osPathname, []byte(name), os.ModePerm))
+	}
+
+	osDirname := filepath.Join(testroot, "root")
+	ensureError(t, os.Symlink("../b", filepath.Join(osDirname, "a", "ok")))
+	ensureError(t, os.Symlink("../../outside", filepath.Join(osDirname, "a", "up")))
+	ensureError(t, os.Symlink(filepath.Join(testroot, "outside"), filepath.Join(osDirname, "abs")))
+
+	expected := []string{
+		osDirname,
+		filepath.Join(osDirname, "a"),
+		filepath.Join(osDirname, "a", "f1"),
+		filepath.Join(osDirname, "a", "ok"),
+		filepath.Join(osDirname, "a", "ok", "f2"),
+		filepath.Join(osDirname, "a", "up"),
+		filepath.Join(osDirname, "abs"),
+		filepath.Join(osDirname, "b"),
+		filepath.Join(osDirname, "b", "f2"),
+	}
+
+	for _, unsorted := range []bool{false, true} {
+		name := "sorted"
+		if unsorted {
+			name = "unsorted"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			var actual []string
+
+			err := Walk(osDirname, &Options{
+				Callback: func(osPathname string, _ *Dirent) error {
+					actual = append(actual, osPathname)
+					return nil
+				},
+				ConfineToRoot:       true,
+				FollowSymbolicLinks: true,
+				Unsorted:            unsorted,
+			})
+			ensureError(t, err)
+
+			ensureStringSlicesMatch(t, actual, expected)
+		})
+	}
+
+	t.Run("without openat2", func(t *testing.T) {
+		// Simulate a kernel without openat2 by checking the pathnames of the
+		// symbolic links being followed instead.
+		var actual []string
+
+		ws := &walkState{ctx: context.Background(), options: &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, osPathname)
+				return nil
+			},
+			ErrorCallback:       defaultErrorCallback,
+			ConfineToRoot:       true,
+			FollowSymbolicLinks: true,
+			ScratchBuffer:       newScratchBuffer(),
+		}}
+		dirent, err := ws.rootDirent(osDirname)
+		ensureError(t, err)
+		ws.close()
+
+		ensureError(t, ws.walk(osDirname, dirent, 0, atFDCWD, nil, ws.options.ScratchBuffer))
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+}
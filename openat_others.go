@@ -0,0 +1,84 @@
+//go:build !linux
+// +build !linux
+
+package godirwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// atFDCWD is the directory descriptor that causes openDirAt to open a pathname
+// relative to the current working directory.
+const atFDCWD = -1
+
+// keepDirOpen is false because children are opened by their full pathnames, so
+// a directory is closed as soon as its entries are read, rather than holding a
+// descriptor for every level of the tree, which on Windows would also prevent
+// renaming or removing the directory from a callback.
+const keepDirOpen = false
+
+// openDirAt opens the directory specified by osPathname. Because the standard
+// library does not provide openat(2) on this operating system, the directory
+// descriptor, name, and follow arguments are ignored, and the directory is
+// opened by its full pathname.
+func openDirAt(_ int, _, osPathname string, _ bool) (*os.File, error) {
+	return os.Open(osPathname)
+}
+
+// statAt returns the file information for the node specified by osPathname,
+// following a symbolic link only when follow is true. Because the standard
+// library does not provide fstatat(2) on this operating system, the directory
+// descriptor and name arguments are ignored.
+func statAt(_ int, _, osPathname string, follow bool) (os.FileInfo, error) {
+	if follow {
+		return os.Stat(osPathname)
+	}
+	return os.Lstat(osPathname)
+}
+
+// readFileAt returns the contents of the ignore file specified by osPathname,
+// or no contents when it is a symbolic link or not a regular file. It checks
+// the node before opening it, and opens it without blocking, so that it is
+// never stuck opening a FIFO. Because the standard library does not provide
+// openat(2) on this operating system, the directory descriptor and name
+// arguments are ignored.
+func readFileAt(_ int, _, osPathname string) ([]byte, error) {
+	fi, err := os.Lstat(osPathname)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, nil
+	}
+	fh, err := os.OpenFile(osPathname, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readIgnoreFile(fh, osPathname)
+	_ = fh.Close()
+	return data, err
+}
+
+// openRootBeneath returns -1, because this operating system does not provide
+// openat2(2), so the confinement of a walk to its root must be checked
+// otherwise.
+func openRootBeneath(string) int { return -1 }
+
+// openDirBeneath opens the directory specified by osPathname like openDirAt,
+// because this operating system does not provide openat2(2).
+func openDirBeneath(dirfd int, name, osPathname string, follow bool) (*os.File, error) {
+	return openDirAt(dirfd, name, osPathname, follow)
+}
+
+// isEscape returns false, because openDirBeneath does not detect escapes on
+// this operating system.
+func isEscape(error) bool { return false }
+
+// dupDirfd returns the directory descriptor, which openDirAt ignores on this
+// operating system.
+func dupDirfd(dirfd int) (int, error) { return dirfd, nil }
+
+// closeDirfd does nothing, because dupDirfd does not duplicate directory
+// descriptors on this operating system.
+func closeDirfd(int) {}
@@ -18,19 +18,35 @@ var MinimumScratchBufferSize = os.Getpagesize()
 func newScratchBuffer() []byte { return make([]byte, MinimumScratchBufferSize) }
 
 func readDirents(osDirname string, scratchBuffer []byte) ([]*Dirent, error) {
-	var entries []*Dirent
-	var workBuffer []byte
-
 	dh, err := os.Open(osDirname)
 	if err != nil {
 		return nil, err
 	}
+	entries, err := readDirentsFrom(dh, osDirname, scratchBuffer, false)
+	if err != nil {
+		_ = dh.Close()
+		return nil, err
+	}
+	if err = dh.Close(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// readDirentsFrom reads every entry of the directory open as dh, whose
+// pathname is osDirname, without closing it. When skipHidden is true, it skips
+// the entries whose names begin with a period.
+func readDirentsFrom(dh *os.File, osDirname string, scratchBuffer []byte, skipHidden bool) ([]*Dirent, error) {
+	var entries []*Dirent
+	var workBuffer []byte
+
 	fd := int(dh.Fd())
 
 	if len(scratchBuffer) < MinimumScratchBufferSize {
 		scratchBuffer = newScratchBuffer()
 	}
 
+	device := new(lazyDevice) // shared by every entry
 	var sde syscall.Dirent
 	for {
 		if len(workBuffer) == 0 {
@@ -40,13 +56,9 @@ func readDirents(osDirname string, scratchBuffer []byte) ([]*Dirent, error) {
 				if err == syscall.EINTR /* || err == unix.EINTR */ {
 					continue
 				}
-				_ = dh.Close()
 				return nil, err
 			}
 			if n <= 0 { // end of directory: normal exit
-				if err = dh.Close(); err != nil {
-					return nil, err
-				}
 				return entries, nil
 			}
 			workBuffer = scratchBuffer[:n] // trim work buffer to number of bytes read
@@ -55,24 +67,24 @@ func readDirents(osDirname string, scratchBuffer []byte) ([]*Dirent, error) {
 		copy((*[unsafe.Sizeof(syscall.Dirent{})]byte)(unsafe.Pointer(&sde))[:], workBuffer)
 		workBuffer = workBuffer[reclen(&sde):] // advance buffer for next iteration through loop
 
-		if inoFromDirent(&sde) == 0 {
+		ino := inoFromDirent(&sde)
+		if ino == 0 {
 			continue // inode set to 0 indicates an entry that was marked as deleted
 		}
 
 		nameSlice := nameFromDirent(&sde)
 		nameLength := len(nameSlice)
 
-		if nameLength == 0 || (nameSlice[0] == '.' && (nameLength == 1 || (nameLength == 2 && nameSlice[1] == '.'))) {
+		if nameLength == 0 || (nameSlice[0] == '.' && (skipHidden || nameLength == 1 || (nameLength == 2 && nameSlice[1] == '.'))) {
 			continue
 		}
 
 		childName := string(nameSlice)
-		mt, err := modeTypeFromDirent(&sde, osDirname, childName)
+		mt, err := modeTypeFromDirent(&sde, fd, osDirname, childName)
 		if err != nil {
-			_ = dh.Close()
 			return nil, err
 		}
-		entries = append(entries, &Dirent{name: childName, path: osDirname, modeType: mt})
+		entries = append(entries, &Dirent{name: childName, path: osDirname, modeType: mt, inode: ino, device: device})
 	}
 }
 
@@ -13,31 +13,47 @@ var MinimumScratchBufferSize = 0
 
 func newScratchBuffer() []byte { return nil }
 
-func readDirents(osDirname string, _ []byte) ([]*Dirent, error) {
+func readDirents(osDirname string, scratchBuffer []byte) ([]*Dirent, error) {
 	dh, err := os.Open(osDirname)
 	if err != nil {
 		return nil, err
 	}
+	entries, err := readDirentsFrom(dh, osDirname, scratchBuffer, false)
+	if err != nil {
+		_ = dh.Close()
+		return nil, err
+	}
+	if err = dh.Close(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
 
+// readDirentsFrom reads every entry of the directory open as dh, whose
+// pathname is osDirname, without closing it. When skipHidden is true, it skips
+// the entries whose names begin with a period.
+func readDirentsFrom(dh *os.File, osDirname string, _ []byte, skipHidden bool) ([]*Dirent, error) {
 	fileinfos, err := dh.Readdir(-1)
 	if err != nil {
-		_ = dh.Close()
 		return nil, err
 	}
 
-	entries := make([]*Dirent, len(fileinfos))
+	entries := make([]*Dirent, 0, len(fileinfos))
+	device := new(lazyDevice) // shared by every entry
 
-	for i, fi := range fileinfos {
-		entries[i] = &Dirent{
-			name:     fi.Name(),
+	for _, fi := range fileinfos {
+		name := fi.Name()
+		if skipHidden && name[0] == '.' {
+			continue
+		}
+		entries = append(entries, &Dirent{
+			name:     name,
 			path:     osDirname,
 			modeType: fi.Mode() & os.ModeType,
-		}
+			device:   device,
+		})
 	}
 
-	if err = dh.Close(); err != nil {
-		return nil, err
-	}
 	return entries, nil
 }
 
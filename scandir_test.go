@@ -1,6 +1,7 @@
 package godirwalk
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -98,3 +99,33 @@ func TestScanner(t *testing.T) {
 		}
 	})
 }
+
+func TestScannerContext(t *testing.T) {
+	t.Run("cancelled before scan", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewScannerContext(ctx, filepath.Join(scaffolingRoot, "d0"))
+		ensureError(t, err, context.Canceled.Error())
+	})
+
+	t.Run("cancelled during scan", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		scanner, err := NewScannerContext(ctx, filepath.Join(scaffolingRoot, "d0"))
+		ensureError(t, err)
+
+		var count int
+
+		for scanner.Scan() {
+			count++
+			cancel()
+		}
+		ensureError(t, scanner.Err(), context.Canceled.Error())
+
+		if got, want := count, 1; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
@@ -4,6 +4,7 @@
 package godirwalk
 
 import (
+	"context"
 	"os"
 	"syscall"
 	"unsafe"
@@ -20,7 +21,10 @@ type Scanner struct {
 	dh            *os.File // used to close directory after done reading
 	de            *Dirent  // most recently decoded directory entry
 	sde           syscall.Dirent
-	fd            int // file descriptor used to read entries from directory
+	device        *lazyDevice     // shared by every entry of the directory
+	fd            int             // file descriptor used to read entries from directory
+	ctx           context.Context // when non-nil, cancellation stops the scan
+	skipHidden    bool            // whether to skip names beginning with a period
 }
 
 // NewScanner returns a new directory Scanner that lazily enumerates
@@ -66,15 +70,41 @@ func NewScannerWithScratchBuffer(osDirname string, scratchBuffer []byte) (*Scann
 	if err != nil {
 		return nil, err
 	}
+	return newScannerFrom(dh, osDirname, scratchBuffer, false), nil
+}
+
+// newScannerFrom returns a new directory Scanner for the directory open as dh,
+// whose pathname is osDirname, which skips the entries whose names begin with
+// a period when skipHidden is true.
+func newScannerFrom(dh *os.File, osDirname string, scratchBuffer []byte, skipHidden bool) *Scanner {
 	if len(scratchBuffer) < MinimumScratchBufferSize {
 		scratchBuffer = newScratchBuffer()
 	}
-	scanner := &Scanner{
+	return &Scanner{
 		scratchBuffer: scratchBuffer,
 		osDirname:     osDirname,
 		dh:            dh,
 		fd:            int(dh.Fd()),
+		device:        new(lazyDevice),
+		skipHidden:    skipHidden,
+	}
+}
+
+// NewScannerContext returns a new directory Scanner that lazily enumerates the
+// contents of a single directory, like NewScanner, but that stops scanning once
+// the provided context is done. When that happens, Scan returns false, the
+// directory is closed, and Err returns the context's error. To prevent resource
+// leaks, caller must invoke either the Scanner's Close or Err method after it
+// has completed scanning a directory.
+func NewScannerContext(ctx context.Context, osDirname string) (*Scanner, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	scanner, err := NewScanner(osDirname)
+	if err != nil {
+		return nil, err
 	}
+	scanner.ctx = ctx
 	return scanner, nil
 }
 
@@ -88,8 +118,13 @@ func (s *Scanner) Close() error {
 // Dirent returns the current directory entry while scanning a directory.
 func (s *Scanner) Dirent() (*Dirent, error) {
 	if s.de == nil {
-		s.de = &Dirent{name: s.childName, path: s.osDirname}
-		s.de.modeType, s.statErr = modeTypeFromDirent(&s.sde, s.osDirname, s.childName)
+		s.de = &Dirent{
+			name:   s.childName,
+			path:   s.osDirname,
+			inode:  inoFromDirent(&s.sde),
+			device: s.device,
+		}
+		s.de.modeType, s.statErr = modeTypeFromDirent(&s.sde, s.fd, s.osDirname, s.childName)
 	}
 	return s.de, s.statErr
 }
@@ -112,9 +147,15 @@ func (s *Scanner) done(err error) {
 	s.scratchBuffer, s.workBuffer = nil, nil
 	s.dh, s.de, s.statErr = nil, nil, nil
 	s.sde = syscall.Dirent{}
-	s.fd = 0
+	s.device = nil
+	s.fd = -1
+	s.ctx = nil
 }
 
+// dirfd returns the descriptor of the directory being scanned, relative to
+// which its children may be opened, until the scan is done.
+func (s *Scanner) dirfd() int { return s.fd }
+
 // Err returns any error associated with scanning a directory. It is
 // normal to call Err after Scan returns false, even though they both
 // ensure Scanner resources are released. Call either this or the
@@ -138,6 +179,13 @@ func (s *Scanner) Scan() bool {
 		return false
 	}
 
+	if s.ctx != nil {
+		if err := s.ctx.Err(); err != nil {
+			s.done(err)
+			return false
+		}
+	}
+
 	s.de = nil
 
 	for {
@@ -171,7 +219,9 @@ func (s *Scanner) Scan() bool {
 		nameSlice := nameFromDirent(&s.sde)
 		nameLength := len(nameSlice)
 
-		if nameLength == 0 || (nameSlice[0] == '.' && (nameLength == 1 || (nameLength == 2 && nameSlice[1] == '.'))) {
+		// Skip "." and "..", and when requested, every other name beginning
+		// with a period, before spending anything else on the entry.
+		if nameLength == 0 || (nameSlice[0] == '.' && (s.skipHidden || nameLength == 1 || (nameLength == 2 && nameSlice[1] == '.'))) {
 			continue
 		}
 
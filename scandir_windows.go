@@ -4,18 +4,22 @@
 package godirwalk
 
 import (
+	"context"
 	"fmt"
 	"os"
 )
 
 // Scanner is an iterator to enumerate the contents of a directory.
 type Scanner struct {
-	osDirname string
-	childName string
-	dh        *os.File // dh is handle to open directory
-	de        *Dirent
-	err       error // err is the error associated with scanning directory
-	childMode os.FileMode
+	osDirname  string
+	childName  string
+	dh         *os.File // dh is handle to open directory
+	de         *Dirent
+	err        error // err is the error associated with scanning directory
+	childMode  os.FileMode
+	ctx        context.Context // when non-nil, cancellation stops the scan
+	device     *lazyDevice     // shared by every entry of the directory
+	skipHidden bool            // whether to skip names beginning with a period
 }
 
 // NewScanner returns a new directory Scanner that lazily enumerates
@@ -51,11 +55,19 @@ func NewScanner(osDirname string) (*Scanner, error) {
 	if err != nil {
 		return nil, err
 	}
-	scanner := &Scanner{
-		osDirname: osDirname,
-		dh:        dh,
+	return newScannerFrom(dh, osDirname, nil, false), nil
+}
+
+// newScannerFrom returns a new directory Scanner for the directory open as dh,
+// whose pathname is osDirname, which skips the entries whose names begin with
+// a period when skipHidden is true.
+func newScannerFrom(dh *os.File, osDirname string, _ []byte, skipHidden bool) *Scanner {
+	return &Scanner{
+		osDirname:  osDirname,
+		dh:         dh,
+		device:     new(lazyDevice),
+		skipHidden: skipHidden,
 	}
-	return scanner, nil
 }
 
 // NewScannerWithScratchBuffer returns a new directory Scanner that
@@ -69,6 +81,24 @@ func NewScannerWithScratchBuffer(osDirname string, scratchBuffer []byte) (*Scann
 	return NewScanner(osDirname)
 }
 
+// NewScannerContext returns a new directory Scanner that lazily enumerates the
+// contents of a single directory, like NewScanner, but that stops scanning once
+// the provided context is done. When that happens, Scan returns false, the
+// directory is closed, and Err returns the context's error. To prevent resource
+// leaks, caller must invoke either the Scanner's Close or Err method after it
+// has completed scanning a directory.
+func NewScannerContext(ctx context.Context, osDirname string) (*Scanner, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	scanner, err := NewScanner(osDirname)
+	if err != nil {
+		return nil, err
+	}
+	scanner.ctx = ctx
+	return scanner, nil
+}
+
 // Close releases resources associated with scanning a directory. Call
 // either this or the Err method when the directory no longer needs to
 // be scanned.
@@ -83,6 +113,7 @@ func (s *Scanner) Dirent() (*Dirent, error) {
 			name:     s.childName,
 			path:     s.osDirname,
 			modeType: s.childMode,
+			device:   s.device,
 		}
 	}
 	return s.de, nil
@@ -104,8 +135,14 @@ func (s *Scanner) done(err error) {
 
 	s.childName, s.osDirname = "", ""
 	s.de, s.dh = nil, nil
+	s.ctx = nil
+	s.device = nil
 }
 
+// dirfd returns -1, because Windows does not open directories relative to
+// other directories.
+func (s *Scanner) dirfd() int { return -1 }
+
 // Err returns any error associated with scanning a directory. It is
 // normal to call Err after Scan returns false, even though they both
 // ensure Scanner resources are released. Call either this or the
@@ -129,21 +166,33 @@ func (s *Scanner) Scan() bool {
 		return false
 	}
 
+	if s.ctx != nil {
+		if err := s.ctx.Err(); err != nil {
+			s.done(err)
+			return false
+		}
+	}
+
 	s.de = nil
 
-	fileinfos, err := s.dh.Readdir(1)
-	if err != nil {
-		s.done(err)
-		return false
-	}
+	for {
+		fileinfos, err := s.dh.Readdir(1)
+		if err != nil {
+			s.done(err)
+			return false
+		}
 
-	if l := len(fileinfos); l != 1 {
-		s.done(fmt.Errorf("expected a single entry rather than %d", l))
-		return false
-	}
+		if l := len(fileinfos); l != 1 {
+			s.done(fmt.Errorf("expected a single entry rather than %d", l))
+			return false
+		}
 
-	fi := fileinfos[0]
-	s.childMode = fi.Mode() & os.ModeType
-	s.childName = fi.Name()
-	return true
+		fi := fileinfos[0]
+		if s.skipHidden && fi.Name()[0] == '.' {
+			continue
+		}
+		s.childMode = fi.Mode() & os.ModeType
+		s.childName = fi.Name()
+		return true
+	}
 }
@@ -1,34 +1,58 @@
 package godirwalk
 
-import "sort"
+import "os"
 
 type scanner interface {
 	Dirent() (*Dirent, error)
 	Err() error
 	Name() string
 	Scan() bool
+
+	// dirfd returns the descriptor of the directory being scanned, relative
+	// to which its children may be opened, or -1 when there is none.
+	dirfd() int
 }
 
 // sortedScanner enumerates through a directory's contents after reading the
-// entire directory and sorting the entries by name. Used by walk to simplify
-// its implementation.
+// entire directory and arranging the entries in the order they are to be
+// visited. Used by walk to simplify its implementation. On operating systems
+// where children are opened relative to their directory, the directory remains
+// open until Err is invoked.
 type sortedScanner struct {
 	dd []*Dirent
 	de *Dirent
+	dh *os.File // open directory; nil once closed
 }
 
-func newSortedScanner(osPathname string, scratchBuffer []byte) (*sortedScanner, error) {
-	deChildren, err := ReadDirents(osPathname, scratchBuffer)
+// newSortedScannerFrom returns a new sortedScanner for the directory open as dh,
+// whose pathname is osPathname, after reading all of its entries, except those
+// whose names begin with a period when skipHidden is true, and ordering them
+// with arrange. Unless keepDirOpen is true, it closes dh after reading it, and it
+// always closes dh when it returns an error.
+func newSortedScannerFrom(dh *os.File, osPathname string, scratchBuffer []byte, skipHidden bool, arrange func(Dirents)) (*sortedScanner, error) {
+	deChildren, err := readDirentsFrom(dh, osPathname, scratchBuffer, skipHidden)
 	if err != nil {
+		_ = dh.Close()
 		return nil, err
 	}
-	sort.Sort(deChildren)
-	return &sortedScanner{dd: deChildren}, nil
+	if !keepDirOpen {
+		if err = dh.Close(); err != nil {
+			return nil, err
+		}
+		dh = nil
+	}
+	arrange(deChildren)
+	return &sortedScanner{dd: deChildren, dh: dh}, nil
 }
 
 func (d *sortedScanner) Err() error {
 	d.dd, d.de = nil, nil
-	return nil
+	if d.dh == nil {
+		return nil
+	}
+	err := d.dh.Close()
+	d.dh = nil
+	return err
 }
 
 func (d *sortedScanner) Dirent() (*Dirent, error) { return d.de, nil }
@@ -42,3 +66,10 @@ func (d *sortedScanner) Scan() bool {
 	}
 	return false
 }
+
+func (d *sortedScanner) dirfd() int {
+	if d.dh == nil {
+		return -1
+	}
+	return int(d.dh.Fd())
+}
@@ -0,0 +1,13 @@
+//go:build go1.20
+// +build go1.20
+
+package godirwalk
+
+import "io/fs"
+
+// SkipAll is used as a return value from WalkFuncs to indicate that the walk is
+// to stop immediately, without visiting any remaining file system nodes, and
+// without invoking any pending PostChildrenCallback functions. It is not
+// returned as an error by any function. Because it is the same value as
+// fs.SkipAll, callback functions may return either one.
+var SkipAll = fs.SkipAll
@@ -0,0 +1,36 @@
+//go:build go1.20
+// +build go1.20
+
+package godirwalk
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalkSkipAllFromFS(t *testing.T) {
+	var actual []string
+
+	err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+		Callback: func(osPathname string, dirent *Dirent) error {
+			if dirent.Name() == "d3" {
+				return fs.SkipAll
+			}
+			actual = append(actual, filepath.FromSlash(osPathname))
+			return nil
+		},
+	})
+
+	ensureError(t, err)
+
+	expected := []string{
+		filepath.Join(scaffolingRoot, "d0/skips"),
+		filepath.Join(scaffolingRoot, "d0/skips/d2"),
+		filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+		filepath.Join(scaffolingRoot, "d0/skips/d2/skip"),
+		filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+	}
+
+	ensureStringSlicesMatch(t, actual, expected)
+}
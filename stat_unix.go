@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package godirwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// deviceFromFileInfo returns the device number of the file system containing
+// the node described by fi, or 0 when fi does not provide it.
+func deviceFromFileInfo(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)
+	}
+	return 0
+}
+
+// inodeFromFileInfo returns the inode number of the node described by fi, or 0
+// when fi does not provide it.
+func inodeFromFileInfo(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package godirwalk
+
+import "os"
+
+// deviceFromFileInfo returns 0, because the file information Windows provides
+// does not include a device number.
+func deviceFromFileInfo(_ os.FileInfo) uint64 { return 0 }
+
+// inodeFromFileInfo returns 0, because the file information Windows provides
+// does not include an inode number.
+func inodeFromFileInfo(_ os.FileInfo) uint64 { return 0 }
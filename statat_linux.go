@@ -0,0 +1,91 @@
+//go:build linux && (386 || amd64 || arm || arm64 || mips || mipsle || ppc64 || ppc64le || riscv64 || s390x)
+// +build linux
+// +build 386 amd64 arm arm64 mips mipsle ppc64 ppc64le riscv64 s390x
+
+package godirwalk
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// atSymlinkNofollow is the value of AT_SYMLINK_NOFOLLOW, which package syscall
+// does not export on every architecture.
+const atSymlinkNofollow = 0x100
+
+// statAt returns the file information for the node with the specified name
+// relative to the directory open as dirfd, rather than by its full pathname, so
+// that it can be obtained for nodes whose pathname is longer than the operating
+// system allows. It follows a symbolic link only when follow is true. The
+// returned information is named after osPathname.
+func statAt(dirfd int, name, osPathname string, follow bool) (os.FileInfo, error) {
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	var flags uintptr
+	if !follow {
+		flags = atSymlinkNofollow
+	}
+	fi := &fileStat{name: filepath.Base(osPathname)}
+	for {
+		_, _, errno := syscall.Syscall6(sysFstatat, uintptr(dirfd), uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&fi.sys)), flags, 0, 0)
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			op := "lstat"
+			if follow {
+				op = "stat"
+			}
+			return nil, &os.PathError{Op: op, Path: osPathname, Err: errno}
+		}
+		return fi, nil
+	}
+}
+
+// fileStat is the os.FileInfo returned by statAt. Like the one returned by
+// os.Lstat, its Sys method returns a *syscall.Stat_t.
+type fileStat struct {
+	name string
+	sys  syscall.Stat_t
+}
+
+func (fs *fileStat) Name() string       { return fs.name }
+func (fs *fileStat) Size() int64        { return int64(fs.sys.Size) }
+func (fs *fileStat) ModTime() time.Time { return time.Unix(fs.sys.Mtim.Unix()) }
+func (fs *fileStat) IsDir() bool        { return fs.Mode().IsDir() }
+func (fs *fileStat) Sys() interface{}   { return &fs.sys }
+
+// Mode converts the mode bits of the stat structure the same way package os
+// does.
+func (fs *fileStat) Mode() os.FileMode {
+	mode := os.FileMode(fs.sys.Mode & 0777)
+	switch fs.sys.Mode & syscall.S_IFMT {
+	case syscall.S_IFBLK:
+		mode |= os.ModeDevice
+	case syscall.S_IFCHR:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	case syscall.S_IFDIR:
+		mode |= os.ModeDir
+	case syscall.S_IFIFO:
+		mode |= os.ModeNamedPipe
+	case syscall.S_IFLNK:
+		mode |= os.ModeSymlink
+	case syscall.S_IFSOCK:
+		mode |= os.ModeSocket
+	}
+	if fs.sys.Mode&syscall.S_ISGID != 0 {
+		mode |= os.ModeSetgid
+	}
+	if fs.sys.Mode&syscall.S_ISUID != 0 {
+		mode |= os.ModeSetuid
+	}
+	if fs.sys.Mode&syscall.S_ISVTX != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
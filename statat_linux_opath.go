@@ -0,0 +1,42 @@
+//go:build linux && !386 && !amd64 && !arm && !arm64 && !mips && !mipsle && !ppc64 && !ppc64le && !riscv64 && !s390x
+// +build linux,!386,!amd64,!arm,!arm64,!mips,!mipsle,!ppc64,!ppc64le,!riscv64,!s390x
+
+package godirwalk
+
+import (
+	"os"
+	"syscall"
+)
+
+// statAt returns the file information for the node with the specified name
+// relative to the directory open as dirfd, rather than by its full pathname, so
+// that it can be obtained for nodes whose pathname is longer than the operating
+// system allows. It follows a symbolic link only when follow is true. The
+// returned information is named after osPathname.
+//
+// The stat structure the kernel fills on this architecture differs from
+// syscall.Stat_t, so rather than calling fstatat(2), statAt opens the node with
+// O_PATH and stats the open file.
+func statAt(dirfd int, name, osPathname string, follow bool) (os.FileInfo, error) {
+	flags := oPATH | syscall.O_CLOEXEC
+	if !follow {
+		flags |= syscall.O_NOFOLLOW
+	}
+	for {
+		fd, err := syscall.Openat(dirfd, name, flags, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			op := "lstat"
+			if follow {
+				op = "stat"
+			}
+			return nil, &os.PathError{Op: op, Path: osPathname, Err: err}
+		}
+		fh := os.NewFile(uintptr(fd), osPathname)
+		fi, err := fh.Stat()
+		_ = fh.Close()
+		return fi, err
+	}
+}
@@ -0,0 +1,8 @@
+//go:build linux && !mips && !mipsle && !mips64 && !mips64le
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package godirwalk
+
+// sysOpenat2 is the number of the openat2(2) system call, which package syscall
+// does not define.
+const sysOpenat2 = 437
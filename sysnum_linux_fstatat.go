@@ -0,0 +1,11 @@
+//go:build linux && (arm64 || riscv64)
+// +build linux
+// +build arm64 riscv64
+
+package godirwalk
+
+import "syscall"
+
+// sysFstatat is the number of the fstatat(2) system call that fills a
+// syscall.Stat_t.
+const sysFstatat = syscall.SYS_FSTATAT
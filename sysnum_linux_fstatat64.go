@@ -0,0 +1,11 @@
+//go:build linux && (386 || arm || mips || mipsle)
+// +build linux
+// +build 386 arm mips mipsle
+
+package godirwalk
+
+import "syscall"
+
+// sysFstatat is the number of the fstatat(2) system call that fills a
+// syscall.Stat_t.
+const sysFstatat = syscall.SYS_FSTATAT64
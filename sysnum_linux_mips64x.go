@@ -0,0 +1,9 @@
+//go:build linux && (mips64 || mips64le)
+// +build linux
+// +build mips64 mips64le
+
+package godirwalk
+
+// sysOpenat2 is the number of the openat2(2) system call, which package syscall
+// does not define.
+const sysOpenat2 = 5437
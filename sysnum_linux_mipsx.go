@@ -0,0 +1,9 @@
+//go:build linux && (mips || mipsle)
+// +build linux
+// +build mips mipsle
+
+package godirwalk
+
+// sysOpenat2 is the number of the openat2(2) system call, which package syscall
+// does not define.
+const sysOpenat2 = 4437
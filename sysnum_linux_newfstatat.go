@@ -0,0 +1,11 @@
+//go:build linux && (amd64 || ppc64 || ppc64le || s390x)
+// +build linux
+// +build amd64 ppc64 ppc64le s390x
+
+package godirwalk
+
+import "syscall"
+
+// sysFstatat is the number of the fstatat(2) system call that fills a
+// syscall.Stat_t.
+const sysFstatat = syscall.SYS_NEWFSTATAT
@@ -1,10 +1,15 @@
 package godirwalk
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
 // Options provide parameters for how the Walk function operates.
@@ -26,14 +31,45 @@ type Options struct {
 	// functions.
 	ErrorCallback func(string, error) ErrorAction
 
+	// DanglingSymlinkCallback specifies a function to be invoked, rather than
+	// the ErrorCallback function, for each symbolic link Walk would follow
+	// but whose referent does not exist. It is invoked with the OS pathname
+	// of the symbolic link along with its target, and its return value
+	// determines whether Walk skips the symbolic link and continues, or halts
+	// and returns an *ErrDanglingSymlink error, so that programs looking for
+	// broken symbolic links need not examine every error. When set to nil or
+	// left as its zero-value, Walk provides an *ErrDanglingSymlink error to
+	// the ErrorCallback function instead.
+	DanglingSymlinkCallback func(osPathname, target string) ErrorAction
+
 	// FollowSymbolicLinks specifies whether Walk will follow symbolic links
 	// that refer to directories. When set to false or left as its zero-value,
 	// Walk will still invoke the callback function with symbolic link nodes,
 	// but if the symbolic link refers to a directory, it will not recurse on
 	// that directory. When set to true, Walk will recurse on symbolic links
 	// that refer to a directory.
+	//
+	// While following symbolic links, Walk keeps track of the directories
+	// between the node it was invoked with and the directory it is reading.
+	// Rather than descending into a directory that is already one of those,
+	// which would never end, Walk invokes the ErrorCallback function with an
+	// *ErrSymlinkCycle error for the symbolic link that leads back to it.
+	// Because Windows does not provide the device and inode numbers needed to
+	// identify directories, Walk cannot detect such cycles on Windows.
+	//
+	// FollowSymbolicLinks is only consulted when SymlinkPolicy is left as its
+	// zero-value, FollowDefault.
 	FollowSymbolicLinks bool
 
+	// SymlinkPolicy specifies which symbolic links to directories Walk will
+	// follow. When left as its zero-value, FollowDefault, Walk follows
+	// symbolic links according to the FollowSymbolicLinks option, so that
+	// programs written before this option existed behave as they always did.
+	// Walk detects symbolic link cycles, as described for FollowSymbolicLinks,
+	// whenever the policy lets it follow symbolic links below the node it was
+	// invoked with.
+	SymlinkPolicy SymlinkPolicy
+
 	// Unsorted controls whether or not Walk will sort the immediate descendants
 	// of a directory by their relative names prior to visiting each of those
 	// entries.
@@ -52,6 +88,27 @@ type Options struct {
 	// next.
 	Unsorted bool
 
+	// Less is an optional function that reports whether the child a of a
+	// directory ought to be visited before its sibling b, allowing the
+	// caller to sort the immediate descendants of each directory in an order
+	// other than the lexical order of their names, such as case-insensitively
+	// or in natural numeric order. Walk visits the children Less considers
+	// equal in lexical order of their names, so that it always traverses the
+	// same directory tree in the same order. Less must not retain a or b, and
+	// may be invoked concurrently when Workers is greater than one. Walk
+	// ignores Less when Unsorted is true.
+	//
+	//    Less: func(a, b *godirwalk.Dirent) bool {
+	//        return strings.ToLower(a.Name()) < strings.ToLower(b.Name())
+	//    },
+	Less func(a, b *Dirent) bool
+
+	// Order specifies the order in which Walk visits the file system nodes of
+	// the hierarchy. When left as its zero-value, DepthFirst, Walk descends
+	// into each directory as soon as it has visited it. See the documentation
+	// of the Order type for the alternatives.
+	Order Order
+
 	// Callback is a required function that Walk will invoke for every file
 	// system node it encounters.
 	Callback WalkFunc
@@ -61,6 +118,31 @@ type Options struct {
 	// processed.
 	PostChildrenCallback WalkFunc
 
+	// ChildrenCallback is an optional function that Walk will invoke for
+	// every directory it reads, after reading it and before visiting any of
+	// its children, with the OS pathname of the directory and its children,
+	// in the order Walk would visit them. Walk visits the children it
+	// returns, in the order it returns them, instead, so it may drop or
+	// reorder children, although it must not return nodes that are not
+	// children of the directory. Returning SkipThis or filepath.SkipDir causes
+	// Walk to visit none of the children, nor invoke the PostChildrenCallback
+	// function for the directory, and returning SkipAll stops the walk. Any
+	// other error is handled like an error returned by the Callback function.
+	// Walk invokes ChildrenCallback for every directory it reads, even those
+	// above MinDepth, and must read each directory entirely before invoking
+	// it, even when Unsorted is true. It may be invoked concurrently when
+	// Workers is greater than one.
+	//
+	//    ChildrenCallback: func(osDirname string, children godirwalk.Dirents) (godirwalk.Dirents, error) {
+	//        for _, child := range children {
+	//            if child.Name() == ".nobackup" {
+	//                return nil, godirwalk.SkipThis
+	//            }
+	//        }
+	//        return children, nil
+	//    },
+	ChildrenCallback func(osDirname string, children Dirents) (Dirents, error)
+
 	// ScratchBuffer is an optional byte slice to use as a scratch buffer for
 	// Walk to use when reading directory entries, to reduce amount of garbage
 	// generation. Not all architectures take advantage of the scratch
@@ -70,11 +152,151 @@ type Options struct {
 	ScratchBuffer []byte
 
 	// AllowNonDirectory causes Walk to bypass the check that ensures it is
-	// being called on a directory node, or when FollowSymbolicLinks is true, a
-	// symbolic link that points to a directory. Leave this value false to have
-	// Walk return an error when called on a non-directory. Set this true to
-	// have Walk run even when called on a non-directory node.
+	// being called on a directory node, or when the symbolic link policy
+	// follows the node Walk is called with, a symbolic link that points to a
+	// directory. Leave this value false to have Walk return an error when
+	// called on a non-directory. Set this true to have Walk run even when
+	// called on a non-directory node.
 	AllowNonDirectory bool
+
+	// Workers specifies the maximum number of goroutines Walk may use to
+	// traverse the file system hierarchy. When set to zero or one, or left as
+	// its zero-value, Walk visits every node from the calling goroutine. When
+	// set greater than one, Walk hands sibling directories off to a bounded
+	// pool of additional goroutines, so that the time one goroutine spends
+	// waiting on the operating system to read a directory can be spent by
+	// another goroutine reading a different directory.
+	//
+	// When Workers is greater than one, the Callback, PostChildrenCallback,
+	// and ErrorCallback functions may be invoked concurrently from multiple
+	// goroutines, and must therefore be safe for concurrent use. Their
+	// semantics are otherwise unchanged. The entries of any one directory are
+	// still visited in order by a single goroutine, so SkipThis and
+	// filepath.SkipDir affect the same nodes they would during a serial walk,
+	// and a directory's PostChildrenCallback is invoked only after all of its
+	// descendants have been processed, even those processed by other
+	// goroutines. The first error that halts the walk stops every goroutine,
+	// and is the error Walk returns.
+	Workers int
+
+	// MaxDepth specifies the maximum depth of the file system nodes Walk will
+	// visit, similar to the -maxdepth option of find(1). The node Walk is
+	// invoked with is at depth zero, its children are at depth one, and so
+	// on. Walk invokes the Callback function for directories at MaxDepth, but
+	// does not read those directories, and therefore does not invoke the
+	// PostChildrenCallback function for them either. When set to zero or left
+	// as its zero-value, Walk descends without limit.
+	MaxDepth int
+
+	// MinDepth specifies the minimum depth of the file system nodes for which
+	// Walk will invoke the Callback and PostChildrenCallback functions,
+	// similar to the -mindepth option of find(1). Walk still traverses the
+	// directories above MinDepth, but does not invoke those functions for
+	// them. When set to zero or left as its zero-value, Walk invokes those
+	// functions for every node it visits, including the node it is invoked
+	// with.
+	MinDepth int
+
+	// OneFileSystem specifies whether Walk will refrain from descending into
+	// directories on a file system other than the one containing the node
+	// Walk is invoked with, similar to the -xdev option of find(1). Walk still
+	// invokes the Callback function for a directory at which it would cross
+	// onto another file system, so the caller can tell which mount points
+	// were skipped, but does not read that directory, and therefore does not
+	// invoke the PostChildrenCallback function for it either. When set to
+	// false or left as its zero-value, Walk descends into directories
+	// regardless of the file system containing them. Because Windows does not
+	// provide device numbers, this option has no effect on Windows.
+	OneFileSystem bool
+
+	// ConfineToRoot specifies whether Walk must refuse to follow any symbolic
+	// link that leads outside of the node Walk is invoked with, regardless of
+	// the symbolic link policy. On Linux 5.6 and later, Walk opens every
+	// directory using openat2(2) with RESOLVE_BENEATH and
+	// RESOLVE_NO_MAGICLINKS, so the kernel guarantees that neither symbolic
+	// links, ".." components, nor magic links such as those in /proc can lead
+	// outside of that node, even when the file system hierarchy is modified
+	// while being walked. On other kernels and operating systems, Walk instead
+	// resolves each symbolic link it would follow and verifies that it leads to
+	// that node or one of its descendants, as it does for the FollowWithinRoot
+	// policy. That check merely compares pathnames, so it is open to a race in
+	// which a directory is renamed, or replaced by a symbolic link, after being
+	// checked but before being opened. Walk still invokes the Callback function
+	// for a symbolic link that leads outside, but does not descend into it.
+	// Because an io/fs file system does not provide a way to resolve symbolic
+	// links, WalkFS does not follow any symbolic link found below its root when
+	// ConfineToRoot is set.
+	ConfineToRoot bool
+
+	// Include specifies optional patterns that restrict the file system nodes
+	// for which Walk invokes the Callback and PostChildrenCallback functions
+	// to those matching at least one of them. Walk still descends into
+	// directories that match none of the patterns, because their descendants
+	// may match.
+	//
+	// Each pattern is matched against the slash-separated pathname of a node
+	// relative to the node Walk is invoked with, such as "cmd/gfind/main.go".
+	// A "**" pattern component matches any number of pathname components,
+	// including none, and every other pattern component matches a single
+	// pathname component, using the syntax of path.Match, so "*.go" only
+	// matches nodes directly below the node Walk is invoked with, while
+	// "**/*.go" matches them at any depth. Include and Exclude are not
+	// matched against the node Walk is invoked with; set MinDepth to one to
+	// omit it. Walk compiles the patterns once per walk, and returns an error
+	// without walking when one is malformed.
+	Include []string
+
+	// Exclude specifies optional patterns, with the same syntax as Include,
+	// for file system nodes Walk must neither visit nor descend into. Walk
+	// does not even open an excluded directory, and Exclude takes precedence
+	// over Include.
+	//
+	//    Exclude: []string{".git", "**/node_modules", "**/*.tmp"},
+	Exclude []string
+
+	// GitIgnore specifies whether Walk will neither visit nor descend into the
+	// file system nodes that git(1) ignores, so that it only visits the nodes
+	// that are tracked, or could be. Walk reads the .gitignore file of each
+	// directory it reads, along with the .git/info/exclude file of each
+	// directory containing a .git directory, and interprets them as described
+	// by gitignore(5): the rules of a directory apply to all of its
+	// descendants, the rules of the directory closest to a node take
+	// precedence, a pattern starting with "!" re-includes what an earlier
+	// pattern ignored, a pattern containing a slash other than at its end is
+	// anchored to the directory of its ignore file, and a pattern ending with a
+	// slash only matches directories. Walk does not open an ignored directory,
+	// so it cannot re-include anything below it, just like git. Walk never
+	// visits a .git directory either, and does not read the ignore files of the
+	// directories above the node Walk is invoked with, nor the global excludes
+	// file of git. Walk treats an ignore file that is a symbolic link, or is
+	// not a regular file, as absent, and returns an error for one larger than
+	// the 100 MiB git allows.
+	GitIgnore bool
+
+	// IgnoreFile specifies the name of an optional additional ignore file,
+	// such as ".ignore", that Walk reads from each directory, whether or not
+	// GitIgnore is true, and interprets like a .gitignore file. The rules of
+	// this file take precedence over those of the .gitignore file in the same
+	// directory.
+	IgnoreFile string
+
+	// SkipHidden specifies whether Walk will neither visit nor descend into
+	// the file system nodes whose names begin with a period, such as .git or
+	// .cache, other than the node Walk is invoked with. Walk skips those
+	// entries as it reads them from a directory, before spending anything
+	// else on them, which is cheaper than returning SkipThis from the
+	// Callback function.
+	SkipHidden bool
+
+	// Filter specifies an optional function that further restricts the file
+	// system nodes for which Walk invokes the Callback and PostChildrenCallback
+	// functions to those for which it returns true. Like Include, and unlike
+	// Exclude, Filter does not stop Walk from descending into directories it
+	// rejects. Walk invokes Filter at most once per node, only for the nodes
+	// that pass MinDepth and Include, and handles an error it returns like one
+	// returned by the Callback function. The match package provides composable
+	// predicates for it, such as those of find(1).
+	Filter func(osPathname string, de *Dirent) (bool, error)
 }
 
 // ErrorAction defines a set of actions the Walk function could take based on
@@ -96,11 +318,131 @@ const (
 	SkipNode
 )
 
+// SymlinkPolicy defines the set of symbolic links to directories Walk follows.
+// See the documentation for the SymlinkPolicy field of the Options structure
+// for more information.
+type SymlinkPolicy int
+
+const (
+	// FollowDefault is the SymlinkPolicy that defers to the
+	// FollowSymbolicLinks option, behaving like FollowAlways when it is true,
+	// and like FollowNever when it is false.
+	FollowDefault SymlinkPolicy = iota
+
+	// FollowNever is the SymlinkPolicy that never follows symbolic links, not
+	// even when the node Walk is invoked with is one.
+	FollowNever
+
+	// FollowRoot is the SymlinkPolicy that follows the node Walk is invoked
+	// with when it is a symbolic link to a directory, but no symbolic link
+	// found below it, similar to the -H option of find(1).
+	FollowRoot
+
+	// FollowAlways is the SymlinkPolicy that follows every symbolic link to a
+	// directory, similar to the -L option of find(1).
+	FollowAlways
+
+	// FollowWithinRoot is the SymlinkPolicy that follows the node Walk is
+	// invoked with when it is a symbolic link to a directory, and any symbolic
+	// link found below it that resolves to that directory or one of its
+	// descendants, but no symbolic link that resolves elsewhere, so that the
+	// walk never leaves the hierarchy it was invoked on. Because an io/fs file
+	// system does not provide a way to resolve symbolic links, WalkFS treats
+	// this policy like FollowRoot.
+	FollowWithinRoot
+)
+
+// Order defines the order in which Walk visits the file system nodes of a
+// hierarchy. See the documentation for the Order field of the Options structure
+// for more information.
+type Order int
+
+const (
+	// DepthFirst is the Order that visits the children of each directory
+	// immediately after the directory itself, before any of its siblings that
+	// follow it.
+	DepthFirst Order = iota
+
+	// BreadthFirst is the Order that visits every node at one depth below the
+	// node Walk is invoked with before any node at the next depth, so that the
+	// shallowest nodes are visited first. Rather than recursing, Walk keeps a
+	// queue of directories it has visited but not yet read. Because the queue
+	// may grow as wide as the hierarchy, Walk does not keep those directories
+	// open, but opens each one by its pathname once it reaches the front of the
+	// queue, so on Linux it neither refuses to follow an ancestor that was
+	// replaced by a symbolic link, unless ConfineToRoot is set, nor can it
+	// traverse hierarchies whose pathnames are longer than PATH_MAX. When
+	// ConfineToRoot is set, only openat2(2), which Linux provides since 5.6,
+	// guarantees that Walk stays beneath the root. On older kernels and other
+	// operating systems, the confinement is only a check of each resolved
+	// pathname against the pathname of the root, which a directory renamed or
+	// replaced by a symbolic link between the check and the open can evade. A
+	// directory has no defined point at which all of its descendants have been
+	// visited, so Walk returns an error without walking when a
+	// PostChildrenCallback function is provided along with this Order. Walk
+	// also ignores the Workers option, and visits every node from the calling
+	// goroutine.
+	BreadthFirst
+
+	// DirectoriesFirst is the Order that walks depth first, like DepthFirst,
+	// but visits the directories among the children of each directory, and
+	// their descendants, before its other children. Symbolic links are not
+	// resolved for this purpose, so they are visited with the other children.
+	// Whether sorted or not, the directories and the other children otherwise
+	// keep their order, but Walk must read each directory entirely before
+	// visiting its children, even when Unsorted is true.
+	DirectoriesFirst
+)
+
 // SkipThis is used as a return value from WalkFuncs to indicate that the file
 // system entry named in the call is to be skipped. It is not returned as an
 // error by any function.
 var SkipThis = errors.New("skip this directory entry")
 
+// ErrSymlinkCycle is the error Walk provides to the ErrorCallback function when
+// following a symbolic link would descend into a directory Walk is already
+// traversing, which would otherwise cause Walk to descend forever. Returning
+// SkipNode from the ErrorCallback function causes Walk to not descend into the
+// symbolic link, but continue with its siblings.
+type ErrSymlinkCycle struct {
+	// Pathname is the OS pathname of the symbolic link that refers to a
+	// directory already being traversed.
+	Pathname string
+
+	// Ancestor is the OS pathname by which Walk is already traversing the
+	// directory Pathname refers to.
+	Ancestor string
+}
+
+func (e *ErrSymlinkCycle) Error() string {
+	return "symbolic link cycle: " + e.Pathname + " refers to ancestor " + e.Ancestor
+}
+
+// ErrDanglingSymlink is the error Walk provides to the ErrorCallback function
+// when it would follow a symbolic link whose referent does not exist, so the
+// caller can tell a dangling symbolic link apart from other errors. See the
+// DanglingSymlinkCallback field of the Options structure for an alternative.
+type ErrDanglingSymlink struct {
+	// Pathname is the OS pathname of the symbolic link.
+	Pathname string
+
+	// Target is the target of the symbolic link, as returned by os.Readlink.
+	// It is empty when walking an io/fs file system, which does not provide
+	// a way to read symbolic links.
+	Target string
+
+	// Err is the error returned when attempting to resolve the symbolic link.
+	Err error
+}
+
+func (e *ErrDanglingSymlink) Error() string {
+	return "dangling symbolic link: " + e.Pathname + " -> " + e.Target
+}
+
+// Unwrap returns the error returned when attempting to resolve the symbolic
+// link.
+func (e *ErrDanglingSymlink) Unwrap() error { return e.Err }
+
 // WalkFunc is the type of the function called for each file system node visited
 // by Walk. The pathname argument will contain the argument to Walk as a prefix;
 // that is, if Walk is called with "dir", which is a directory containing the
@@ -125,6 +467,12 @@ var SkipThis = errors.New("skip this directory entry")
 // supplied ErrorCallback function is not invoked with filepath.SkipDir when the
 // Callback or PostChildrenCallback functions return that special value.
 //
+// Similarly, when the Callback or PostChildrenCallback functions return the
+// special value SkipAll, Walk stops immediately, without visiting any remaining
+// nodes or invoking the PostChildrenCallback function for the directories it
+// has not finished processing, and returns nil rather than an error. The
+// supplied ErrorCallback function is not invoked with SkipAll either.
+//
 // One arguably confusing aspect of the filepath.WalkFunc API that this library
 // must emulate is how a caller tells Walk to skip file system entries or
 // directories. With both filepath.Walk and this Walk, when a callback function
@@ -183,6 +531,23 @@ type WalkFunc func(osPathname string, directoryEntry *Dirent) error
 // invoke os.Stat for every node it encounters, but rather obtains the file
 // system node type when it reads the parent directory.
 //
+// On Linux, Walk opens each directory relative to the already open directory
+// containing it, using openat(2), and refuses to open a symbolic link in place
+// of a directory unless the symbolic link policy follows it. A directory that
+// is renamed, or replaced by a symbolic link, while Walk is traversing the file
+// system hierarchy therefore cannot redirect Walk outside of that hierarchy,
+// which matters for privileged programs walking directories other users may
+// modify. The pathnames provided to the callback functions are still formed by
+// joining the names of the nodes to the pathname Walk was invoked with. Walk
+// also queries the file system for information about a node relative to the
+// directory containing it, so it can traverse hierarchies whose pathnames are
+// longer than PATH_MAX, although the Info and IsDirOrSymlinkToDir methods of a
+// Dirent, which query the file system by pathname when invoked by a callback,
+// cannot. Because each directory remains open while its descendants are
+// walked, Walk uses one file descriptor for each level of the hierarchy it is
+// traversing. On other operating systems, Walk opens each directory by its
+// pathname.
+//
 // If a runtime error occurs, either from the operating system or from the
 // upstream Callback or PostChildrenCallback functions, processing typically
 // halts. However, when an ErrorCallback function is provided in the provided
@@ -215,34 +580,54 @@ type WalkFunc func(osPathname string, directoryEntry *Dirent) error
 //        }
 //    }
 func Walk(pathname string, options *Options) error {
+	return WalkContext(context.Background(), pathname, options)
+}
+
+// WalkContext walks the file tree rooted at the specified directory exactly
+// like Walk, but stops the walk once the provided context is done. The context
+// is checked before each file system node is visited, so that a cancelled
+// context stops the walk promptly, after closing any directories it has open,
+// and returns the context's error. Because cancellation is not a runtime error
+// of the file system, the context's error is never sent to the ErrorCallback
+// function.
+//
+//    func handler(w http.ResponseWriter, r *http.Request) {
+//        err := godirwalk.WalkContext(r.Context(), dirname, &godirwalk.Options{
+//            Callback: func(osPathname string, de *godirwalk.Dirent) error {
+//                _, err := fmt.Fprintf(w, "%s %s\n", de.ModeType(), osPathname)
+//                return err
+//            },
+//        })
+//        if err != nil {
+//            log.Printf("cannot index %s: %s", dirname, err)
+//        }
+//    }
+func WalkContext(ctx context.Context, pathname string, options *Options) error {
+	return walkContext(ctx, filepath.Clean(pathname), options, nil)
+}
+
+// walkContext walks the file tree rooted at the specified pathname, which has
+// already been cleaned, reading directories with reader when it is not nil, or
+// otherwise directly from the operating system.
+func walkContext(ctx context.Context, pathname string, options *Options, reader dirReader) error {
 	if options == nil || options.Callback == nil {
 		return errors.New("cannot walk without non-nil options and Callback function")
 	}
-
-	pathname = filepath.Clean(pathname)
-
-	var fi os.FileInfo
-	var err error
-
-	if options.FollowSymbolicLinks {
-		fi, err = os.Stat(pathname)
-	} else {
-		fi, err = os.Lstat(pathname)
+	if options.Order == BreadthFirst && options.PostChildrenCallback != nil {
+		return errors.New("cannot walk breadth first with PostChildrenCallback function")
 	}
-	if err != nil {
+
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	mode := fi.Mode()
-	if !options.AllowNonDirectory && mode&os.ModeDir == 0 {
-		return fmt.Errorf("cannot Walk non-directory: %s", pathname)
-	}
+	ws := &walkState{options: options, reader: reader, ctx: ctx, done: ctx.Done()}
 
-	dirent := &Dirent{
-		name:     filepath.Base(pathname),
-		path:     filepath.Dir(pathname),
-		modeType: mode & os.ModeType,
+	dirent, err := ws.rootDirent(pathname)
+	if err != nil {
+		return err
 	}
+	defer ws.close()
 
 	if len(options.ScratchBuffer) < MinimumScratchBufferSize {
 		options.ScratchBuffer = newScratchBuffer()
@@ -255,10 +640,19 @@ func Walk(pathname string, options *Options) error {
 		options.ErrorCallback = defaultErrorCallback
 	}
 
-	err = walk(pathname, dirent, options)
+	if options.Order == BreadthFirst {
+		err = ws.walkBreadthFirst(pathname, dirent)
+	} else {
+		if options.Workers > 1 {
+			// The calling goroutine is the first worker, so only create tokens
+			// for the additional goroutines.
+			ws.workers = make(chan struct{}, options.Workers-1)
+		}
+		err = ws.walk(pathname, dirent, 0, atFDCWD, nil, options.ScratchBuffer)
+	}
 	switch err {
-	case nil, SkipThis, filepath.SkipDir:
-		// silence SkipThis and filepath.SkipDir for top level
+	case nil, SkipThis, filepath.SkipDir, SkipAll:
+		// silence SkipThis, filepath.SkipDir, and SkipAll for top level
 		debug("no error of significance: %v\n", err)
 		return nil
 	default:
@@ -271,71 +665,797 @@ func Walk(pathname string, options *Options) error {
 // halt upon any operating system error.
 func defaultErrorCallback(_ string, _ error) ErrorAction { return Halt }
 
+// dirReader reads directories and resolves symbolic links for a walk of a file
+// system other than that of the operating system, such as an io/fs.FS. Like
+// with io/fs, the pathnames it accepts are always slash-separated.
+type dirReader interface {
+	// readDirents returns the immediate descendants of the specified
+	// directory, sorted by name.
+	readDirents(osDirname string) (Dirents, error)
+
+	// stat returns the file information of the specified node, following
+	// symbolic links.
+	stat(osPathname string) (os.FileInfo, error)
+
+	// lstat returns the file information of the specified node, not
+	// following symbolic links.
+	lstat(osPathname string) (os.FileInfo, error)
+
+	// readFile returns the contents of the specified file.
+	readFile(osPathname string) ([]byte, error)
+}
+
+// walkState holds the state shared by every goroutine taking part in a single
+// invocation of Walk.
+type walkState struct {
+	options *Options
+	reader  dirReader       // reads directories; nil for the operating system
+	workers chan struct{}   // tokens for additional goroutines; nil when serial
+	ctx     context.Context // context that stops the walk once done
+	done    <-chan struct{} // ctx.Done(), which is nil when ctx cannot be done
+	root    os.FileInfo     // file information of the node the walk started at
+	device  uint64          // device number of the file system of the root
+	policy  SymlinkPolicy   // resolved policy, never FollowDefault
+	realDir string          // absolute pathname of the root, with symlinks resolved
+	rootfd  int             // root open for openDirBeneath; -1 when not confined by the kernel
+	rootDir string          // pathname of the root, relative to which rootfd is opened
+	filter  *globFilter     // compiled Include and Exclude patterns; nil when none
+
+	mu   sync.Mutex
+	halt error // first error that halted a parallel walk
+}
+
+// rootDirent returns the Dirent for the file system node at the root of a walk,
+// ensuring it is a directory unless the options allow otherwise.
+func (ws *walkState) rootDirent(pathname string) (*Dirent, error) {
+	options := ws.options
+
+	var err error
+	if ws.filter, err = newGlobFilter(pathname, options); err != nil {
+		return nil, err
+	}
+
+	ws.rootfd = -1
+	ws.policy = options.SymlinkPolicy
+	if ws.policy == FollowDefault {
+		if options.FollowSymbolicLinks {
+			ws.policy = FollowAlways
+		} else {
+			ws.policy = FollowNever
+		}
+	}
+	if ws.reader != nil && (ws.policy == FollowWithinRoot || options.ConfineToRoot && ws.followsBelowRoot()) {
+		ws.policy = FollowRoot // cannot resolve where io/fs symbolic links lead
+	}
+
+	var fi os.FileInfo
+
+	if ws.reader != nil {
+		fi, err = ws.reader.stat(pathname)
+	} else if ws.policy != FollowNever {
+		if fi, err = os.Stat(pathname); os.IsNotExist(err) {
+			err = danglingSymlink(pathname, err)
+		}
+	} else {
+		fi, err = os.Lstat(pathname)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ws.policy == FollowWithinRoot || options.ConfineToRoot && ws.followsBelowRoot() {
+		if ws.realDir, err = filepath.EvalSymlinks(pathname); err == nil {
+			ws.realDir, err = filepath.Abs(ws.realDir)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mode := fi.Mode()
+	if !options.AllowNonDirectory && mode&os.ModeDir == 0 {
+		return nil, fmt.Errorf("cannot Walk non-directory: %s", pathname)
+	}
+
+	ws.root, ws.device = fi, deviceFromFileInfo(fi)
+
+	if ws.reader != nil {
+		return &Dirent{
+			name:     path.Base(pathname),
+			path:     path.Dir(pathname),
+			modeType: mode & os.ModeType,
+			reader:   ws.reader,
+		}, nil
+	}
+	de := &Dirent{
+		name:     filepath.Base(pathname),
+		path:     filepath.Dir(pathname),
+		modeType: mode & os.ModeType,
+		inode:    inodeFromFileInfo(fi),
+	}
+	if ws.policy == FollowNever {
+		de.info = fi // Info does not follow symbolic links either
+	}
+	if options.ConfineToRoot && mode&os.ModeDir != 0 {
+		ws.rootfd, ws.rootDir = openRootBeneath(pathname), pathname
+	}
+	return de, nil
+}
+
+// close releases the resources held for the duration of the walk.
+func (ws *walkState) close() {
+	if ws.rootfd >= 0 {
+		closeDirfd(ws.rootfd)
+		ws.rootfd = -1
+	}
+}
+
+// danglingSymlink returns an *ErrDanglingSymlink error for the specified
+// pathname, for which resolving returned err, when it is a symbolic link, and
+// otherwise returns err.
+func danglingSymlink(osPathname string, err error) error {
+	target, rerr := os.Readlink(osPathname)
+	if rerr != nil {
+		return err // not a symbolic link, or cannot tell
+	}
+	return &ErrDanglingSymlink{Pathname: osPathname, Target: target, Err: err}
+}
+
+// errorAction returns the action to take for the error that occurred while
+// reading the directory specified by osPathname, which it obtains from the
+// DanglingSymlinkCallback function for dangling symbolic links when provided,
+// and from the ErrorCallback function otherwise.
+func (ws *walkState) errorAction(osPathname string, err error) ErrorAction {
+	if e, ok := err.(*ErrDanglingSymlink); ok && ws.options.DanglingSymlinkCallback != nil {
+		return ws.options.DanglingSymlinkCallback(osPathname, e.Target)
+	}
+	return ws.options.ErrorCallback(osPathname, err)
+}
+
+// followsBelowRoot reports whether the walk follows any symbolic links found
+// below its root.
+func (ws *walkState) followsBelowRoot() bool {
+	return ws.policy == FollowAlways || ws.policy == FollowWithinRoot
+}
+
+// withinRoot reports whether the symbolic link specified by osPathname resolves
+// to the root of the walk or one of its descendants.
+func (ws *walkState) withinRoot(osPathname string) (bool, error) {
+	target, err := filepath.EvalSymlinks(osPathname)
+	if err == nil {
+		target, err = filepath.Abs(target)
+	}
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(ws.realDir, target)
+	if err != nil {
+		return false, nil // on another volume
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// ancestor is a directory between the root of a walk and the directory being
+// read, identified by its device and inode numbers, so that following a
+// symbolic link back to it can be detected. It also holds the ignore rules
+// that apply to the descendants of the directory, so that an ancestor is
+// created for a directory with ignore files even when its device and inode
+// numbers are not needed, in which case they are zero.
+type ancestor struct {
+	device, inode uint64
+	osPathname    string
+	ignore        *ignoreRules // innermost ignore rules; nil when none
+	parent        *ancestor
+}
+
+// enter returns the ancestor for the directory specified by osPathname and its
+// file information, whose parent is the ancestor for the directory containing
+// it. It returns an *ErrSymlinkCycle error when the directory is already one of
+// its ancestors, and returns parent when the file system does not identify
+// directories by device and inode numbers.
+func (ws *walkState) enter(osPathname string, fi os.FileInfo, parent *ancestor) (*ancestor, error) {
+	device, inode := deviceFromFileInfo(fi), inodeFromFileInfo(fi)
+	if device == 0 && inode == 0 {
+		return parent, nil
+	}
+	for a := parent; a != nil; a = a.parent {
+		if a.device == device && a.inode == inode {
+			return nil, &ErrSymlinkCycle{Pathname: osPathname, Ancestor: a.osPathname}
+		}
+	}
+	self := &ancestor{device: device, inode: inode, osPathname: osPathname, parent: parent}
+	if parent != nil {
+		self.ignore = parent.ignore
+	}
+	return self, nil
+}
+
+// nodeInfo returns the file information for the node specified by osPathname
+// and dirent, found in the directory open as dirfd, following the node when it
+// is a symbolic link and follow is true. Querying relative to dirfd rather than
+// by pathname allows obtaining the information for nodes whose pathname is
+// longer than the operating system allows. What it learns is remembered by the
+// Dirent, so that the callbacks need not query the file system again.
+func (ws *walkState) nodeInfo(dirfd int, osPathname string, dirent *Dirent, follow bool) (os.FileInfo, error) {
+	name := dirent.name
+	if dirfd == atFDCWD {
+		name = osPathname // the root of the walk
+	}
+	if !follow || !dirent.IsSymlink() {
+		if dirent.info != nil || ws.reader != nil {
+			return dirent.Info()
+		}
+		fi, err := statAt(dirfd, name, osPathname, false)
+		if err != nil {
+			return nil, err
+		}
+		dirent.info = fi
+		return fi, nil
+	}
+	var fi os.FileInfo
+	var err error
+	if ws.reader != nil {
+		fi, err = ws.reader.stat(osPathname)
+	} else {
+		fi, err = statAt(dirfd, name, osPathname, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dirent.setLinkToDir(fi.IsDir())
+	return fi, nil
+}
+
+// isDirOrSymlinkToDir returns whether the node specified by osPathname and
+// dirent, found in the directory open as dirfd, is a directory or a symbolic
+// link to a directory, like the IsDirOrSymlinkToDir method of the Dirent, but
+// querying the file system relative to dirfd.
+func (ws *walkState) isDirOrSymlinkToDir(dirfd int, osPathname string, dirent *Dirent) (bool, error) {
+	if !dirent.IsSymlink() || dirent.toDir != linkToDirUnknown {
+		return dirent.IsDirOrSymlinkToDir()
+	}
+	fi, err := ws.nodeInfo(dirfd, osPathname, dirent, true)
+	if err != nil {
+		return false, err
+	}
+	return fi.IsDir(), nil
+}
+
+// join returns the pathname of the child with the specified name of the
+// specified directory.
+func (ws *walkState) join(osDirname, name string) string {
+	if ws.reader != nil {
+		return path.Join(osDirname, name)
+	}
+	return filepath.Join(osDirname, name)
+}
+
+// halted returns the error that ought to stop the walk, either because its
+// context is done or because another goroutine halted a parallel walk, or nil
+// when the walk ought to continue.
+func (ws *walkState) halted() error {
+	if ws.done != nil {
+		select {
+		case <-ws.done:
+			return ws.ctx.Err()
+		default:
+		}
+	}
+	if ws.workers == nil {
+		return nil
+	}
+	ws.mu.Lock()
+	err := ws.halt
+	ws.mu.Unlock()
+	return err
+}
+
+// setHalted records the error that halts a parallel walk, unless another
+// goroutine already recorded one.
+func (ws *walkState) setHalted(err error) {
+	ws.mu.Lock()
+	if ws.halt == nil {
+		ws.halt = err
+	}
+	ws.mu.Unlock()
+}
+
+// excluded reports whether the walk ought to neither visit nor descend into the
+// file system node specified by osPathname and dirent, found depth levels below
+// the root of the walk in the directory identified by parent, because it
+// matches an Exclude pattern or is ignored.
+func (ws *walkState) excluded(osPathname string, dirent *Dirent, depth int, parent *ancestor) bool {
+	if depth == 0 {
+		return false
+	}
+	if ws.filter != nil && ws.filter.excluded(osPathname) {
+		return true
+	}
+	if ws.options.GitIgnore && dirent.name == ".git" {
+		return true
+	}
+	return parent != nil && parent.ignore != nil && parent.ignore.ignores(osPathname, dirent.IsDir())
+}
+
+// invokes reports whether the walk invokes the Callback and PostChildrenCallback
+// functions for the file system node specified by osPathname and dirent, found
+// depth levels below the root of the walk, which depends on the minimum depth,
+// the Include patterns, and the Filter function, whose error it returns.
+func (ws *walkState) invokes(osPathname string, dirent *Dirent, depth int) (bool, error) {
+	if depth < ws.options.MinDepth {
+		return false, nil
+	}
+	if ws.filter != nil && depth > 0 && !ws.filter.included(osPathname) {
+		return false, nil
+	}
+	if ws.options.Filter == nil {
+		return true, nil
+	}
+	return ws.options.Filter(osPathname, dirent)
+}
+
+// visit invokes the Callback function for the file system node specified by
+// osPathname and dirent, found depth levels below the root of the walk, unless
+// the walk does not invoke it for that node, and reports whether it did. It
+// returns SkipThis when the ErrorCallback function chooses to skip the node
+// after the Filter or Callback function returned an error.
+func (ws *walkState) visit(osPathname string, dirent *Dirent, depth int) (bool, error) {
+	options := ws.options
+
+	invoke, err := ws.invokes(osPathname, dirent, depth)
+	if err == nil {
+		if !invoke {
+			return false, nil
+		}
+		err = options.Callback(osPathname, dirent)
+	}
+	if err == nil || err == SkipThis || err == filepath.SkipDir || err == SkipAll {
+		return invoke, err
+	}
+	if action := options.ErrorCallback(osPathname, err); action == SkipNode {
+		return invoke, SkipThis
+	}
+	return invoke, err
+}
+
 // walk recursively traverses the file system node specified by pathname and the
-// Dirent.
-func walk(osPathname string, dirent *Dirent, options *Options) error {
-	err := options.Callback(osPathname, dirent)
+// Dirent, found depth levels below the root of the walk in the directory open
+// as dirfd and identified by parent, using scratchBuffer, which belongs to the
+// calling goroutine, when reading directories.
+func (ws *walkState) walk(osPathname string, dirent *Dirent, depth int, dirfd int, parent *ancestor, scratchBuffer []byte) error {
+	options := ws.options
+
+	if ws.excluded(osPathname, dirent, depth, parent) {
+		return nil
+	}
+	invoked, err := ws.visit(osPathname, dirent, depth)
 	if err != nil {
-		if err == SkipThis || err == filepath.SkipDir {
+		return err
+	}
+
+	ds, self, err := ws.readDirectory(osPathname, dirent, depth, dirfd, parent, scratchBuffer)
+	if err != nil {
+		if err == SkipAll {
 			return err
 		}
-		if action := options.ErrorCallback(osPathname, err); action == SkipNode {
+		if action := ws.errorAction(osPathname, err); action == SkipNode {
 			return nil
 		}
 		return err
 	}
+	if ds == nil {
+		return nil // not a directory, or walk ought not descend into it
+	}
+
+	// Children handed off to other goroutines must all complete before either
+	// returning or invoking the post children callback for this directory.
+	var wg sync.WaitGroup
+	err = ws.walkChildren(osPathname, ds, depth+1, self, scratchBuffer, &wg)
+	if err2 := ds.Err(); err == nil {
+		err = err2
+	}
+	wg.Wait()
+	if err == nil {
+		err = ws.halted()
+	}
+	if err != nil {
+		return err
+	}
+
+	if options.PostChildrenCallback == nil || !invoked {
+		return nil
+	}
+
+	err = options.PostChildrenCallback(osPathname, dirent)
+	if err == nil || err == filepath.SkipDir || err == SkipAll {
+		return err
+	}
+
+	if action := options.ErrorCallback(osPathname, err); action == SkipNode {
+		return nil
+	}
+	return err
+}
+
+// readDirectory returns a scanner that enumerates the children of the file
+// system node specified by osPathname and dirent, found depth levels below the
+// root of the walk in the directory open as dirfd, when the walk ought to
+// descend into that node, along with the ancestor its children ought to be
+// walked with, given parent, the ancestor the node was walked with. It returns
+// a nil scanner and nil error when the node is not a directory, or when the
+// walk ought not descend into it.
+func (ws *walkState) readDirectory(osPathname string, dirent *Dirent, depth int, dirfd int, parent *ancestor, scratchBuffer []byte) (scanner, *ancestor, error) {
+	options := ws.options
+
+	if options.MaxDepth > 0 && depth >= options.MaxDepth {
+		return nil, nil, nil // do not even resolve symbolic links at maximum depth
+	}
+
+	var fi os.FileInfo // information about the directory, once obtained
+	if depth == 0 {
+		fi = ws.root
+	}
 
 	if dirent.IsSymlink() {
-		if !options.FollowSymbolicLinks {
-			return nil
+		if !ws.followsBelowRoot() {
+			return nil, nil, nil
 		}
 		// Does this symlink point to a directory?
-		info, err := os.Stat(osPathname)
-		if err != nil {
-			if action := options.ErrorCallback(osPathname, err); action == SkipNode {
-				return nil
+		var err error
+		if fi, err = ws.nodeInfo(dirfd, osPathname, dirent, true); err != nil {
+			if os.IsNotExist(err) {
+				if ws.reader != nil {
+					err = &ErrDanglingSymlink{Pathname: osPathname, Err: err}
+				} else {
+					err = danglingSymlink(osPathname, err)
+				}
 			}
-			return err
+			return nil, nil, err
 		}
-		if !info.IsDir() {
-			return nil
+		if !fi.IsDir() {
+			return nil, nil, nil
+		}
+		if ws.rootfd < 0 && (ws.policy == FollowWithinRoot || options.ConfineToRoot) {
+			within, err := ws.withinRoot(osPathname)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !within {
+				return nil, nil, nil // do not leave the hierarchy being walked
+			}
 		}
 	} else if !dirent.IsDir() {
-		return nil
+		return nil, nil, nil
 	}
 
 	// If get here, then specified pathname refers to a directory or a
 	// symbolic link to a directory.
 
-	var ds scanner
+	if fi == nil && (options.OneFileSystem || ws.followsBelowRoot()) {
+		var err error
+		if fi, err = ws.nodeInfo(dirfd, osPathname, dirent, false); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if options.OneFileSystem && deviceFromFileInfo(fi) != ws.device {
+		return nil, nil, nil // do not cross onto another file system
+	}
+
+	self := parent
+	if ws.followsBelowRoot() {
+		var err error
+		if self, err = ws.enter(osPathname, fi, parent); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ds, err := ws.scanDirectory(osPathname, dirent, depth, dirfd, scratchBuffer)
+	if err != nil || ds == nil {
+		return nil, nil, err
+	}
+
+	if ws.ignoring() {
+		if self, err = ws.ignore(ds.dirfd(), osPathname, parent, self); err != nil {
+			_ = ds.Err()
+			return nil, nil, err
+		}
+	}
+	return ds, self, nil
+}
+
+// scanDirectory returns a scanner that enumerates the children of the directory
+// specified by osPathname and dirent, found depth levels below the root of the
+// walk in the directory open as dirfd. It returns a nil scanner and nil error
+// when the walk ought not descend into the directory after all.
+func (ws *walkState) scanDirectory(osPathname string, dirent *Dirent, depth int, dirfd int, scratchBuffer []byte) (scanner, error) {
+	options := ws.options
 
-	if options.Unsorted {
+	if ws.reader != nil {
+		deChildren, err := ws.reader.readDirents(osPathname)
+		if err != nil {
+			return nil, err
+		}
+		if options.SkipHidden {
+			deChildren = withoutHidden(deChildren)
+		}
+		ws.arrange(deChildren)
+		return ws.children(osPathname, &sortedScanner{dd: deChildren})
+	}
+
+	dh, err := ws.openDir(dirfd, osPathname, dirent, depth)
+	if err != nil {
+		if isEscape(err) {
+			return nil, nil // do not leave the hierarchy being walked
+		}
+		return nil, err
+	}
+
+	if options.Unsorted && options.Order != DirectoriesFirst && options.ChildrenCallback == nil {
 		// When upstream does not request a sorted iteration, it's more memory
 		// efficient to read a single child at a time from the file system.
-		ds, err = NewScanner(osPathname)
-	} else {
-		// When upstream wants a sorted iteration, we must read the entire
-		// directory and sort through the child names, and then iterate on each
-		// child.
-		ds, err = newSortedScanner(osPathname, options.ScratchBuffer)
+		return newScannerFrom(dh, osPathname, nil, options.SkipHidden), nil
 	}
+
+	// When upstream wants a sorted iteration, we must read the entire
+	// directory and sort through the child names, and then iterate on each
+	// child.
+	ds, err := newSortedScannerFrom(dh, osPathname, scratchBuffer, options.SkipHidden, ws.arrange)
 	if err != nil {
-		if action := options.ErrorCallback(osPathname, err); action == SkipNode {
-			return nil
+		return nil, err
+	}
+	return ws.children(osPathname, ds)
+}
+
+// withoutHidden returns the children whose names do not begin with a period,
+// reusing the storage of children.
+func withoutHidden(children Dirents) Dirents {
+	visible := children[:0]
+	for _, child := range children {
+		if child.name[0] != '.' {
+			visible = append(visible, child)
+		}
+	}
+	return visible
+}
+
+// children invokes the ChildrenCallback function, when provided, with the
+// children of the directory specified by osPathname that ds enumerates, and has
+// ds enumerate the children it returns instead. It returns a nil scanner, after
+// releasing ds, when the walk ought not descend into the directory.
+func (ws *walkState) children(osPathname string, ds *sortedScanner) (scanner, error) {
+	if ws.options.ChildrenCallback == nil {
+		return ds, nil
+	}
+	dd, err := ws.options.ChildrenCallback(osPathname, ds.dd)
+	if err != nil {
+		_ = ds.Err()
+		if err == SkipThis || err == filepath.SkipDir {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ds.dd = dd
+	return ds, nil
+}
+
+// arrange orders the children of a directory the way the walk visits them.
+func (ws *walkState) arrange(children Dirents) {
+	if less := ws.options.Less; less != nil && !ws.options.Unsorted {
+		sort.Slice(children, func(i, j int) bool {
+			a, b := children[i], children[j]
+			return less(a, b) || !less(b, a) && a.name < b.name
+		})
+	} else if !ws.options.Unsorted {
+		sort.Sort(children)
+	}
+	if ws.options.Order == DirectoriesFirst {
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].IsDir() && !children[j].IsDir()
+		})
+	}
+}
+
+// openDir opens the directory specified by osPathname and dirent, found depth
+// levels below the root of the walk in the directory open as dirfd, or by its
+// pathname when dirfd is atFDCWD. It opens the directory relative to the
+// directory containing it, rather than by its pathname, and only opens a
+// symbolic link when deliberately following it, so that a directory renamed or
+// replaced by a symbolic link while being walked cannot lead the walk
+// elsewhere. When the kernel confines the walk to its root, it also refuses to
+// open a symbolic link that leads outside of the root.
+func (ws *walkState) openDir(dirfd int, osPathname string, dirent *Dirent, depth int) (*os.File, error) {
+	if depth == 0 {
+		return openDirAt(atFDCWD, osPathname, osPathname, ws.policy != FollowNever)
+	}
+	if ws.rootfd < 0 {
+		name := dirent.name
+		if dirfd == atFDCWD {
+			name = osPathname
 		}
+		return openDirAt(dirfd, name, osPathname, dirent.IsSymlink())
+	}
+	if !dirent.IsSymlink() && dirfd != atFDCWD {
+		return openDirBeneath(dirfd, dirent.name, osPathname, false)
+	}
+	// Resolve a symbolic link relative to the root rather than the directory
+	// containing it, so that it may refer to any directory below the root.
+	rel, err := filepath.Rel(ws.rootDir, osPathname)
+	if err != nil {
+		return nil, err
+	}
+	return openDirBeneath(ws.rootfd, rel, osPathname, dirent.IsSymlink())
+}
+
+// pendingDir is a directory that a breadth first walk has visited, but whose
+// children it has yet to visit.
+type pendingDir struct {
+	osPathname string
+	dirent     *Dirent
+	depth      int
+	parent     *ancestor // ancestor the directory was walked with
+}
+
+// walkBreadthFirst traverses the file system hierarchy rooted at the node
+// specified by osPathname and dirent, visiting every node at one depth before
+// any node at the next depth.
+func (ws *walkState) walkBreadthFirst(osPathname string, dirent *Dirent) error {
+	if _, err := ws.visit(osPathname, dirent, 0); err != nil {
 		return err
 	}
+	queue := []pendingDir{{osPathname: osPathname, dirent: dirent}}
+	for len(queue) > 0 {
+		pd := queue[0]
+		queue[0] = pendingDir{} // release for garbage collection
+		queue = queue[1:]
+
+		var err error
+		if queue, err = ws.walkPending(pd, queue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkPending visits each of the children of the pending directory pd, and
+// returns queue with those of its children the walk may descend into appended.
+func (ws *walkState) walkPending(pd pendingDir, queue []pendingDir) ([]pendingDir, error) {
+	options := ws.options
+
+	if err := ws.halted(); err != nil {
+		return nil, err
+	}
+	ds, self, err := ws.readDirectory(pd.osPathname, pd.dirent, pd.depth, atFDCWD, pd.parent, options.ScratchBuffer)
+	if err != nil {
+		if err == SkipAll {
+			return nil, err
+		}
+		if action := ws.errorAction(pd.osPathname, err); action == SkipNode {
+			return queue, nil
+		}
+		return nil, err
+	}
+	if ds == nil {
+		return queue, nil // not a directory, or walk ought not descend into it
+	}
+
+	queue, err = ws.visitChildren(pd.osPathname, ds, pd.depth+1, self, queue)
+	if err2 := ds.Err(); err == nil {
+		err = err2
+	}
+	return queue, err
+}
+
+// visitChildren visits each of the children enumerated by ds, which are found
+// depth levels below the root of the walk in the directory identified by
+// parent, and returns queue with those of the children the walk may descend
+// into appended.
+func (ws *walkState) visitChildren(osPathname string, ds scanner, depth int, parent *ancestor, queue []pendingDir) ([]pendingDir, error) {
+	options := ws.options
+	dirfd := ds.dirfd()
 
 	for ds.Scan() {
+		if err := ws.halted(); err != nil {
+			return nil, err
+		}
 		deChild, err := ds.Dirent()
-		osChildname := filepath.Join(osPathname, deChild.name)
+		osChildname := ws.join(osPathname, deChild.name)
+		if err != nil {
+			if action := options.ErrorCallback(osChildname, err); action == SkipNode {
+				return queue, nil
+			}
+			return nil, err
+		}
+		if ws.excluded(osChildname, deChild, depth, parent) {
+			continue
+		}
+		switch _, err = ws.visit(osChildname, deChild, depth); err {
+		case nil:
+			if deChild.IsDir() || deChild.IsSymlink() {
+				queue = append(queue, pendingDir{osPathname: osChildname, dirent: deChild, depth: depth, parent: parent})
+			}
+			continue
+		case SkipThis:
+			continue
+		case filepath.SkipDir:
+			// Same as when walking depth first: skip a directory, or the
+			// remaining siblings of a non-directory.
+			isDir, err := ws.isDirOrSymlinkToDir(dirfd, osChildname, deChild)
+			if err != nil {
+				if action := options.ErrorCallback(osChildname, err); action == SkipNode {
+					continue
+				}
+				return nil, err
+			}
+			if isDir {
+				continue
+			}
+			return queue, nil
+		default:
+			return nil, err
+		}
+	}
+	return queue, nil
+}
+
+// walkChildren visits each of the children enumerated by ds, which are found
+// depth levels below the root of the walk in the directory identified by
+// parent. When walking in parallel and a worker token is available, child
+// directories are walked by another goroutine, which is tracked by wg.
+func (ws *walkState) walkChildren(osPathname string, ds scanner, depth int, parent *ancestor, scratchBuffer []byte, wg *sync.WaitGroup) error {
+	options := ws.options
+	dirfd := ds.dirfd()
+
+	for ds.Scan() {
+		if err := ws.halted(); err != nil {
+			return err
+		}
+		deChild, err := ds.Dirent()
+		osChildname := ws.join(osPathname, deChild.name)
 		if err != nil {
 			if action := options.ErrorCallback(osChildname, err); action == SkipNode {
 				return nil
 			}
 			return err
 		}
-		err = walk(osChildname, deChild, options)
+		// Only hand off directories to other goroutines. Other node types are
+		// visited from this goroutine, because filepath.SkipDir returned for
+		// them must stop processing of their remaining siblings.
+		if ws.workers != nil && deChild.IsDir() {
+			select {
+			case ws.workers <- struct{}{}:
+				// The other goroutine may still need to open the child after
+				// this directory is closed, so it gets its own descriptor,
+				// unless there is none, such as when walking an io/fs.FS.
+				childfd, duplicated := dirfd, dirfd >= 0
+				if duplicated {
+					if childfd, err = dupDirfd(dirfd); err != nil {
+						<-ws.workers // walk the child from this goroutine
+						break
+					}
+				}
+				wg.Add(1)
+				go func(osChildname string, deChild *Dirent) {
+					defer func() {
+						if duplicated {
+							closeDirfd(childfd)
+						}
+						<-ws.workers
+						wg.Done()
+					}()
+					switch err := ws.walk(osChildname, deChild, depth, childfd, parent, newScratchBuffer()); err {
+					case nil, SkipThis, filepath.SkipDir:
+						// directory skipped; siblings continue
+					default:
+						ws.setHalted(err)
+					}
+				}(osChildname, deChild)
+				continue
+			default:
+				// no idle worker: walk the child from this goroutine
+			}
+		}
+		err = ws.walk(osChildname, deChild, depth, dirfd, parent, scratchBuffer)
 		debug("osChildname: %q; error: %v\n", osChildname, err)
 		if err == nil || err == SkipThis {
 			continue
@@ -347,7 +1467,7 @@ func walk(osPathname string, dirent *Dirent, options *Options) error {
 		// directory, stop processing that directory but continue processing
 		// siblings.  When received on a non-directory, stop processing
 		// remaining siblings.
-		isDir, err := deChild.IsDirOrSymlinkToDir()
+		isDir, err := ws.isDirOrSymlinkToDir(dirfd, osChildname, deChild)
 		if err != nil {
 			if action := options.ErrorCallback(osChildname, err); action == SkipNode {
 				continue // ignore and continue with next sibling
@@ -359,21 +1479,5 @@ func walk(osPathname string, dirent *Dirent, options *Options) error {
 		}
 		// continue processing remaining siblings
 	}
-	if err = ds.Err(); err != nil {
-		return err
-	}
-
-	if options.PostChildrenCallback == nil {
-		return nil
-	}
-
-	err = options.PostChildrenCallback(osPathname, dirent)
-	if err == nil || err == filepath.SkipDir {
-		return err
-	}
-
-	if action := options.ErrorCallback(osPathname, err); action == SkipNode {
-		return nil
-	}
-	return err
+	return nil
 }
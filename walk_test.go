@@ -1,9 +1,15 @@
 package godirwalk
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -312,6 +318,354 @@ func TestPostChildrenCallback(t *testing.T) {
 	ensureStringSlicesMatch(t, actual, expected)
 }
 
+func TestWalkWorkers(t *testing.T) {
+	t.Run("same as serial", func(t *testing.T) {
+		osDirname := filepath.Join(scaffolingRoot, "d0")
+		expected := godirwalkWalk(t, osDirname)
+
+		var mu sync.Mutex
+		var actual []string
+		err := Walk(osDirname, &Options{
+			Callback: func(osPathname string, dirent *Dirent) error {
+				if dirent.Name() == "skip" {
+					return filepath.SkipDir
+				}
+				mu.Lock()
+				actual = append(actual, filepath.FromSlash(osPathname))
+				mu.Unlock()
+				return nil
+			},
+			Workers: 4,
+		})
+
+		ensureError(t, err)
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("post children callback after descendants", func(t *testing.T) {
+		var mu sync.Mutex
+		var visited []string
+		posted := make(map[string]int) // index into visited when post children callback invoked
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0"), &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				mu.Lock()
+				visited = append(visited, osPathname)
+				mu.Unlock()
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				mu.Lock()
+				posted[osPathname] = len(visited)
+				mu.Unlock()
+				return nil
+			},
+			Workers: 4,
+		})
+
+		ensureError(t, err)
+
+		for osDirname, index := range posted {
+			for _, osPathname := range visited[index:] {
+				if strings.HasPrefix(osPathname, osDirname+string(os.PathSeparator)) {
+					t.Errorf("GOT: %q visited after post children callback for %q", osPathname, osDirname)
+				}
+			}
+		}
+		if got, want := len(posted), 8; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("halt", func(t *testing.T) {
+		var mu sync.Mutex
+		var actual []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0"), &Options{
+			Callback: func(osPathname string, dirent *Dirent) error {
+				if dirent.Name() == "d1" {
+					return errors.New("halt at d1")
+				}
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				mu.Lock()
+				actual = append(actual, osPathname)
+				mu.Unlock()
+				return nil
+			},
+			Workers: 4,
+		})
+
+		ensureError(t, err, "halt at d1")
+
+		// Because the walk was halted before all of its children were visited,
+		// the post children callback for the root must not be invoked.
+		for _, osPathname := range actual {
+			if osPathname == filepath.Join(scaffolingRoot, "d0") {
+				t.Errorf("GOT: %q; WANT: no post children callback for root", osPathname)
+			}
+		}
+	})
+}
+
+func TestWalkContext(t *testing.T) {
+	t.Run("cancelled before walk", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var callbackVisited bool
+
+		err := WalkContext(ctx, filepath.Join(scaffolingRoot, "d0"), &Options{
+			Callback: func(_ string, _ *Dirent) error {
+				callbackVisited = true
+				return nil
+			},
+		})
+
+		ensureError(t, err, context.Canceled.Error())
+		if got, want := callbackVisited, false; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("cancelled during walk", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var actual []string
+		var errorCallbackVisited bool
+
+		err := WalkContext(ctx, filepath.Join(scaffolingRoot, "d0"), &Options{
+			Callback: func(osPathname string, dirent *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				if dirent.Name() == "d1" {
+					cancel()
+				}
+				return nil
+			},
+			ErrorCallback: func(_ string, _ error) ErrorAction {
+				errorCallbackVisited = true
+				return SkipNode
+			},
+			Unsorted: true,
+		})
+
+		ensureError(t, err, context.Canceled.Error())
+		if got, want := errorCallbackVisited, false; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := actual[len(actual)-1], filepath.Join(scaffolingRoot, "d0/d1"); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("cancelled during parallel walk", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		err := WalkContext(ctx, filepath.Join(scaffolingRoot, "d0"), &Options{
+			Callback: func(_ string, dirent *Dirent) error {
+				if dirent.Name() == "d1" {
+					cancel()
+				}
+				return nil
+			},
+			Workers: 4,
+		})
+
+		ensureError(t, err, context.Canceled.Error())
+	})
+}
+
+func TestWalkDepth(t *testing.T) {
+	t.Run("max depth", func(t *testing.T) {
+		var actual, posted []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				posted = append(posted, filepath.FromSlash(osPathname))
+				return nil
+			},
+			MaxDepth: 1,
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, posted, []string{filepath.Join(scaffolingRoot, "d0/skips")})
+	})
+
+	t.Run("min depth", func(t *testing.T) {
+		var actual, posted []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				posted = append(posted, filepath.FromSlash(osPathname))
+				return nil
+			},
+			MinDepth: 2,
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip/f5"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, posted, []string{filepath.Join(scaffolingRoot, "d0/skips/d3/skip")})
+	})
+
+	t.Run("min and max depth", func(t *testing.T) {
+		var actual []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/symlinks"), &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			ErrorCallback: func(_ string, _ error) ErrorAction {
+				return SkipNode // ignore the dangling symbolic link
+			},
+			FollowSymbolicLinks: true,
+			MaxDepth:            2,
+			MinDepth:            2,
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSF1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toD1/f2"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+}
+
+func TestWalkSkipAll(t *testing.T) {
+	t.Run("callback", func(t *testing.T) {
+		var actual, posted []string
+		var errorCallbackVisited bool
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			Callback: func(osPathname string, dirent *Dirent) error {
+				if dirent.Name() == "skip" {
+					return SkipAll
+				}
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			ErrorCallback: func(_ string, _ error) ErrorAction {
+				errorCallbackVisited = true
+				return Halt
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				posted = append(posted, filepath.FromSlash(osPathname))
+				return nil
+			},
+		})
+
+		ensureError(t, err)
+
+		if got, want := errorCallbackVisited, false; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, posted, nil)
+	})
+
+	t.Run("post children callback", func(t *testing.T) {
+		var actual, posted []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, dirent *Dirent) error {
+				posted = append(posted, filepath.FromSlash(osPathname))
+				if dirent.Name() == "d2" {
+					return SkipAll
+				}
+				return nil
+			},
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, posted, []string{filepath.Join(scaffolingRoot, "d0/skips/d2")})
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		var mu sync.Mutex
+		var posted []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0"), &Options{
+			Callback: func(_ string, dirent *Dirent) error {
+				if dirent.Name() == "f2" {
+					return SkipAll
+				}
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				mu.Lock()
+				posted = append(posted, filepath.FromSlash(osPathname))
+				mu.Unlock()
+				return nil
+			},
+			Workers: 4,
+		})
+
+		ensureError(t, err)
+
+		for _, osPathname := range posted {
+			switch osPathname {
+			case filepath.Join(scaffolingRoot, "d0"), filepath.Join(scaffolingRoot, "d0/d1"):
+				t.Errorf("GOT: %q; WANT: no post children callback", osPathname)
+			}
+		}
+	})
+}
+
 const flameIterations = 10
 
 var goPrefix = filepath.Join(os.Getenv("GOPATH"), "src")
@@ -354,3 +708,891 @@ func BenchmarkFlameGraphGodirwalk(b *testing.B) {
 		_ = godirwalkWalk(b, goPrefix)
 	}
 }
+
+func TestWalkSymlinkCycle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic link cycles cannot be detected on Windows")
+	}
+
+	// Create a hierarchy with a symbolic link back to its root, and another
+	// that refers to a sibling directory, which is not a cycle.
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+	ensureError(t, err)
+	defer os.RemoveAll(testroot)
+
+	ensureError(t, os.MkdirAll(filepath.Join(testroot, "a/b"), os.ModePerm))
+	ensureError(t, os.Mkdir(filepath.Join(testroot, "c"), os.ModePerm))
+	ensureError(t, os.Symlink("../..", filepath.Join(testroot, "a/b/loop")))
+	ensureError(t, os.Symlink("../c", filepath.Join(testroot, "a/toC")))
+
+	t.Run("halt", func(t *testing.T) {
+		err := Walk(testroot, &Options{
+			Callback:            func(string, *Dirent) error { return nil },
+			FollowSymbolicLinks: true,
+		})
+
+		var cycle *ErrSymlinkCycle
+		if !errors.As(err, &cycle) {
+			t.Fatalf("GOT: %v; WANT: %T", err, cycle)
+		}
+		if got, want := cycle.Pathname, filepath.Join(testroot, "a/b/loop"); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := cycle.Ancestor, testroot; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("skip node", func(t *testing.T) {
+		var actual, errored []string
+
+		err := Walk(testroot, &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, osPathname)
+				return nil
+			},
+			ErrorCallback: func(osPathname string, err error) ErrorAction {
+				var cycle *ErrSymlinkCycle
+				if errors.As(err, &cycle) {
+					errored = append(errored, osPathname)
+					return SkipNode
+				}
+				return Halt
+			},
+			FollowSymbolicLinks: true,
+		})
+		ensureError(t, err)
+
+		expected := []string{
+			testroot,
+			filepath.Join(testroot, "a"),
+			filepath.Join(testroot, "a/b"),
+			filepath.Join(testroot, "a/b/loop"),
+			filepath.Join(testroot, "a/toC"),
+			filepath.Join(testroot, "c"),
+		}
+		ensureStringSlicesMatch(t, actual, expected)
+
+		expected = []string{filepath.Join(testroot, "a/b/loop")}
+		ensureStringSlicesMatch(t, errored, expected)
+	})
+
+	t.Run("walker", func(t *testing.T) {
+		w, err := NewWalker(testroot, &Options{FollowSymbolicLinks: true})
+		ensureError(t, err)
+
+		for w.Next() {
+		}
+
+		var cycle *ErrSymlinkCycle
+		if !errors.As(w.Err(), &cycle) {
+			t.Fatalf("GOT: %v; WANT: %T", w.Err(), cycle)
+		}
+	})
+}
+
+func TestWalkSymlinkPolicy(t *testing.T) {
+	policyWalk := func(t *testing.T, osDirname string, policy SymlinkPolicy) []string {
+		t.Helper()
+		var actual []string
+		err := Walk(osDirname, &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			ErrorCallback: func(osPathname string, err error) ErrorAction {
+				if filepath.Base(osPathname) == "nothing" {
+					return SkipNode
+				}
+				return Halt
+			},
+			FollowSymbolicLinks: true, // ignored unless policy is FollowDefault
+			SymlinkPolicy:       policy,
+		})
+		ensureError(t, err)
+		return actual
+	}
+
+	notFollowed := []string{
+		filepath.Join(scaffolingRoot, "d0/symlinks"),
+		filepath.Join(scaffolingRoot, "d0/symlinks/d4"),
+		filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1"),
+		filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSF1"),
+		filepath.Join(scaffolingRoot, "d0/symlinks/nothing"),
+		filepath.Join(scaffolingRoot, "d0/symlinks/toAbs"),
+		filepath.Join(scaffolingRoot, "d0/symlinks/toD1"),
+		filepath.Join(scaffolingRoot, "d0/symlinks/toF1"),
+	}
+
+	t.Run("default", func(t *testing.T) {
+		actual := policyWalk(t, filepath.Join(scaffolingRoot, "d0/symlinks"), FollowDefault)
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/symlinks"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1/f2"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSF1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/nothing"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toAbs"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toD1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toD1/f2"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toF1"),
+		}
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("never", func(t *testing.T) {
+		actual := policyWalk(t, filepath.Join(scaffolingRoot, "d0/symlinks"), FollowNever)
+		ensureStringSlicesMatch(t, actual, notFollowed)
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/symlinks/toD1"), &Options{
+			Callback:      func(string, *Dirent) error { return nil },
+			SymlinkPolicy: FollowNever,
+		})
+		ensureError(t, err, "non-directory")
+	})
+
+	t.Run("root", func(t *testing.T) {
+		actual := policyWalk(t, filepath.Join(scaffolingRoot, "d0/symlinks"), FollowRoot)
+		ensureStringSlicesMatch(t, actual, notFollowed)
+
+		actual = policyWalk(t, filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1"), FollowRoot)
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1/f2"),
+		}
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("within root", func(t *testing.T) {
+		// Every symbolic link to a directory below d0/symlinks refers to d0/d1,
+		// which is outside of it.
+		actual := policyWalk(t, filepath.Join(scaffolingRoot, "d0/symlinks"), FollowWithinRoot)
+		ensureStringSlicesMatch(t, actual, notFollowed)
+
+		// Walking d0 itself, the same symbolic links remain inside.
+		actual = policyWalk(t, filepath.Join(scaffolingRoot, "d0"), FollowWithinRoot)
+		expected := policyWalk(t, filepath.Join(scaffolingRoot, "d0"), FollowNever)
+		expected = append(expected,
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1/f2"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toD1/f2"),
+		)
+		sort.Strings(actual)
+		sort.Strings(expected)
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+}
+
+func TestWalkDanglingSymlink(t *testing.T) {
+	osDirname := filepath.Join(scaffolingRoot, "d0/symlinks")
+
+	t.Run("error callback", func(t *testing.T) {
+		var dangling *ErrDanglingSymlink
+
+		err := Walk(osDirname, &Options{
+			Callback: func(string, *Dirent) error { return nil },
+			ErrorCallback: func(osPathname string, err error) ErrorAction {
+				if !errors.As(err, &dangling) {
+					t.Errorf("GOT: %v; WANT: %T", err, dangling)
+				}
+				return SkipNode
+			},
+			FollowSymbolicLinks: true,
+		})
+		ensureError(t, err)
+
+		if dangling == nil {
+			t.Fatalf("GOT: %v; WANT: %T", dangling, dangling)
+		}
+		if got, want := dangling.Pathname, filepath.Join(osDirname, "nothing"); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := dangling.Target, filepath.FromSlash("../f0"); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := os.IsNotExist(errors.Unwrap(dangling)), true; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("dangling symlink callback", func(t *testing.T) {
+		var actual []string
+
+		err := Walk(osDirname, &Options{
+			Callback: func(string, *Dirent) error { return nil },
+			DanglingSymlinkCallback: func(osPathname, target string) ErrorAction {
+				actual = append(actual, osPathname+" -> "+target)
+				return SkipNode
+			},
+			FollowSymbolicLinks: true,
+		})
+		ensureError(t, err)
+
+		expected := []string{filepath.Join(osDirname, "nothing") + " -> " + filepath.FromSlash("../f0")}
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("halt", func(t *testing.T) {
+		err := Walk(osDirname, &Options{
+			Callback: func(string, *Dirent) error { return nil },
+			DanglingSymlinkCallback: func(string, string) ErrorAction {
+				return Halt
+			},
+			FollowSymbolicLinks: true,
+		})
+
+		var dangling *ErrDanglingSymlink
+		if !errors.As(err, &dangling) {
+			t.Errorf("GOT: %v; WANT: %T", err, dangling)
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		err := Walk(filepath.Join(osDirname, "nothing"), &Options{
+			Callback:            func(string, *Dirent) error { return nil },
+			FollowSymbolicLinks: true,
+		})
+
+		var dangling *ErrDanglingSymlink
+		if !errors.As(err, &dangling) {
+			t.Errorf("GOT: %v; WANT: %T", err, dangling)
+		}
+	})
+}
+
+func TestWalkOrder(t *testing.T) {
+	orderWalk := func(t *testing.T, options *Options) []string {
+		t.Helper()
+		var actual []string
+		options.Callback = func(osPathname string, _ *Dirent) error {
+			actual = append(actual, filepath.FromSlash(osPathname))
+			return nil
+		}
+		ensureError(t, Walk(filepath.Join(scaffolingRoot, "d0/skips"), options))
+		return actual
+	}
+
+	// ensureOrder ensures actual lists the same pathnames as expected, in the
+	// same order.
+	ensureOrder := func(t *testing.T, actual, expected []string) {
+		t.Helper()
+		if got, want := len(actual), len(expected); got != want {
+			t.Fatalf("GOT: %q; WANT: %q", actual, expected)
+		}
+		for i := range actual {
+			if got, want := actual[i], expected[i]; got != want {
+				t.Errorf("GOT: %v; WANT: %v", got, want)
+			}
+		}
+	}
+
+	t.Run("breadth first", func(t *testing.T) {
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip/f5"),
+		}
+
+		ensureOrder(t, orderWalk(t, &Options{Order: BreadthFirst}), expected)
+		ensureOrder(t, walkerWalk(t, filepath.Join(scaffolingRoot, "d0/skips"), &Options{Order: BreadthFirst}), []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+		})
+
+		ensureStringSlicesMatch(t, orderWalk(t, &Options{Order: BreadthFirst, Unsorted: true}), expected)
+	})
+
+	t.Run("breadth first skip", func(t *testing.T) {
+		var actual []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			Callback: func(osPathname string, dirent *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				switch dirent.Name() {
+				case "d2":
+					return SkipThis
+				case "f4":
+					return filepath.SkipDir
+				}
+				return nil
+			},
+			Order: BreadthFirst,
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+		}
+
+		ensureOrder(t, actual, expected)
+	})
+
+	t.Run("breadth first skip all", func(t *testing.T) {
+		var actual []string
+
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			Callback: func(osPathname string, dirent *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				if dirent.Name() == "f3" {
+					return SkipAll
+				}
+				return nil
+			},
+			Order: BreadthFirst,
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+		}
+
+		ensureOrder(t, actual, expected)
+	})
+
+	t.Run("breadth first rejects post children callback", func(t *testing.T) {
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			Callback: func(_ string, _ *Dirent) error {
+				t.Error("GOT: callback; WANT: no callback")
+				return nil
+			},
+			PostChildrenCallback: func(_ string, _ *Dirent) error { return nil },
+			Order:                BreadthFirst,
+		})
+		ensureError(t, err, "breadth first", "PostChildrenCallback")
+	})
+
+	t.Run("directories first", func(t *testing.T) {
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip/f5"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+		}
+
+		ensureOrder(t, orderWalk(t, &Options{Order: DirectoriesFirst}), expected)
+
+		// Without sorting, each directory must still be visited before its
+		// siblings that are not directories.
+		actual := orderWalk(t, &Options{Order: DirectoriesFirst, Unsorted: true})
+		ensureStringSlicesMatch(t, actual, expected)
+		for i, osPathname := range actual {
+			if filepath.Base(osPathname) == "skip" && filepath.Base(filepath.Dir(osPathname)) == "d3" {
+				if got, want := filepath.Base(actual[i-1]), "d3"; got != want {
+					t.Errorf("GOT: %v; WANT: %v", got, want)
+				}
+			}
+		}
+	})
+}
+
+func TestWalkLess(t *testing.T) {
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+	ensureError(t, err)
+	defer os.RemoveAll(testroot)
+
+	for _, name := range []string{"file10", "File2", "file1", "dir/b", "dir/A"} {
+		osPathname := filepath.Join(testroot, filepath.FromSlash(name))
+		ensureError(t, os.MkdirAll(filepath.Dir(osPathname), os.ModePerm))
+		ensureError(t, ioutil.WriteFile(osPathname, []byte(name), os.ModePerm))
+	}
+
+	// byLength sorts shorter names first, leaving ties to the names.
+	byLength := func(a, b *Dirent) bool { return len(a.Name()) < len(b.Name()) }
+
+	lessWalk := func(t *testing.T, options *Options) []string {
+		t.Helper()
+		var mu sync.Mutex
+		var actual []string
+		options.Callback = func(osPathname string, _ *Dirent) error {
+			if osPathname != testroot {
+				mu.Lock()
+				actual = append(actual, filepath.ToSlash(osPathname[len(testroot)+1:]))
+				mu.Unlock()
+			}
+			return nil
+		}
+		if options.Less == nil {
+			options.Less = byLength
+		}
+		ensureError(t, Walk(testroot, options))
+		return actual
+	}
+
+	expected := []string{"dir", "dir/A", "dir/b", "File2", "file1", "file10"}
+
+	t.Run("walk", func(t *testing.T) {
+		actual := lessWalk(t, &Options{})
+		if got, want := strings.Join(actual, " "), strings.Join(expected, " "); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		actual := lessWalk(t, &Options{Workers: 4})
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("walker", func(t *testing.T) {
+		w, err := NewWalker(testroot, &Options{Less: byLength})
+		ensureError(t, err)
+		var actual []string
+		for w.Next() {
+			if w.Path() != testroot {
+				actual = append(actual, filepath.ToSlash(w.Path()[len(testroot)+1:]))
+			}
+		}
+		ensureError(t, w.Close())
+		if got, want := strings.Join(actual, " "), strings.Join(expected, " "); got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+
+	t.Run("ignored when unsorted", func(t *testing.T) {
+		actual := lessWalk(t, &Options{
+			Less: func(_, _ *Dirent) bool {
+				t.Error("GOT: Less invoked; WANT: not invoked")
+				return false
+			},
+			Unsorted: true,
+		})
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+}
+
+func TestChildrenCallback(t *testing.T) {
+	childrenWalk := func(t *testing.T, options *Options) ([]string, []string, error) {
+		t.Helper()
+		var actual, posted []string
+		options.Callback = func(osPathname string, _ *Dirent) error {
+			actual = append(actual, filepath.FromSlash(osPathname))
+			return nil
+		}
+		if options.Order != BreadthFirst {
+			options.PostChildrenCallback = func(osPathname string, _ *Dirent) error {
+				posted = append(posted, filepath.FromSlash(osPathname))
+				return nil
+			}
+		}
+		err := Walk(filepath.Join(scaffolingRoot, "d0/skips"), options)
+		return actual, posted, err
+	}
+
+	t.Run("filter and reorder", func(t *testing.T) {
+		for _, options := range []*Options{{}, {Unsorted: true}, {Order: BreadthFirst}} {
+			var dirnames []string
+
+			actual, _, err := childrenWalk(t, &Options{
+				ChildrenCallback: func(osDirname string, children Dirents) (Dirents, error) {
+					dirnames = append(dirnames, filepath.FromSlash(osDirname))
+					var kept Dirents
+					for _, child := range children {
+						if child.Name() != "skip" {
+							kept = append(kept, child)
+						}
+					}
+					sort.Slice(kept, func(i, j int) bool { return kept[i].Name() > kept[j].Name() })
+					return kept, nil
+				},
+				Order:    options.Order,
+				Unsorted: options.Unsorted,
+			})
+
+			ensureError(t, err)
+
+			ensureStringSlicesMatch(t, dirnames, []string{
+				filepath.Join(scaffolingRoot, "d0/skips"),
+				filepath.Join(scaffolingRoot, "d0/skips/d2"),
+				filepath.Join(scaffolingRoot, "d0/skips/d3"),
+			})
+
+			expected := []string{
+				filepath.Join(scaffolingRoot, "d0/skips"),
+				filepath.Join(scaffolingRoot, "d0/skips/d3"),
+				filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+				filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+				filepath.Join(scaffolingRoot, "d0/skips/d2"),
+				filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+				filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			}
+			if options.Order == BreadthFirst {
+				expected = []string{
+					filepath.Join(scaffolingRoot, "d0/skips"),
+					filepath.Join(scaffolingRoot, "d0/skips/d3"),
+					filepath.Join(scaffolingRoot, "d0/skips/d2"),
+					filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+					filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+					filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+					filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+				}
+			}
+
+			if got, want := strings.Join(actual, "\n"), strings.Join(expected, "\n"); got != want {
+				t.Errorf("GOT:\n%v\nWANT:\n%v", got, want)
+			}
+		}
+	})
+
+	t.Run("skip directory with marker", func(t *testing.T) {
+		actual, posted, err := childrenWalk(t, &Options{
+			ChildrenCallback: func(_ string, children Dirents) (Dirents, error) {
+				for _, child := range children {
+					if child.Name() == "f4" {
+						return nil, SkipThis
+					}
+				}
+				return children, nil
+			},
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2/z1"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, posted, []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+		})
+	})
+
+	t.Run("skip all", func(t *testing.T) {
+		for _, order := range []Order{DepthFirst, BreadthFirst} {
+			actual, posted, err := childrenWalk(t, &Options{
+				ChildrenCallback: func(osDirname string, children Dirents) (Dirents, error) {
+					if filepath.Base(osDirname) == "d2" {
+						return nil, SkipAll
+					}
+					return children, nil
+				},
+				Order: order,
+			})
+
+			ensureError(t, err)
+
+			expected := []string{
+				filepath.Join(scaffolingRoot, "d0/skips"),
+				filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			}
+			if order == BreadthFirst {
+				expected = append(expected, filepath.Join(scaffolingRoot, "d0/skips/d3"))
+			}
+
+			ensureStringSlicesMatch(t, actual, expected)
+			ensureStringSlicesMatch(t, posted, nil)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var errored []string
+
+		actual, _, err := childrenWalk(t, &Options{
+			ChildrenCallback: func(osDirname string, children Dirents) (Dirents, error) {
+				if filepath.Base(osDirname) == "d2" {
+					return nil, errors.New("cannot process d2")
+				}
+				return children, nil
+			},
+			ErrorCallback: func(osPathname string, err error) ErrorAction {
+				errored = append(errored, filepath.FromSlash(osPathname))
+				ensureError(t, err, "cannot process d2")
+				return SkipNode
+			},
+		})
+
+		ensureError(t, err)
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips"),
+			filepath.Join(scaffolingRoot, "d0/skips/d2"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/skip/f5"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, errored, []string{filepath.Join(scaffolingRoot, "d0/skips/d2")})
+
+		_, _, err = childrenWalk(t, &Options{
+			ChildrenCallback: func(_ string, _ Dirents) (Dirents, error) {
+				return nil, errors.New("halt")
+			},
+		})
+		ensureError(t, err, "halt")
+	})
+}
+
+func TestWalkIncludeExclude(t *testing.T) {
+	osDirname := filepath.Join(scaffolingRoot, "d0/skips")
+
+	patternWalk := func(t *testing.T, options *Options) ([]string, []string) {
+		t.Helper()
+		var actual, read []string
+		options.Callback = func(osPathname string, _ *Dirent) error {
+			actual = append(actual, filepath.FromSlash(osPathname))
+			return nil
+		}
+		options.ChildrenCallback = func(osDirname string, children Dirents) (Dirents, error) {
+			read = append(read, filepath.FromSlash(osDirname))
+			return children, nil
+		}
+		ensureError(t, Walk(osDirname, options))
+		return actual, read
+	}
+
+	t.Run("exclude", func(t *testing.T) {
+		for _, order := range []Order{DepthFirst, BreadthFirst} {
+			actual, read := patternWalk(t, &Options{Exclude: []string{"d3", "**/z?"}, Order: order})
+
+			expected := []string{
+				osDirname,
+				filepath.Join(osDirname, "d2"),
+				filepath.Join(osDirname, "d2/f3"),
+				filepath.Join(osDirname, "d2/skip"),
+			}
+
+			ensureStringSlicesMatch(t, actual, expected)
+			ensureStringSlicesMatch(t, read, []string{osDirname, filepath.Join(osDirname, "d2")})
+		}
+	})
+
+	t.Run("include", func(t *testing.T) {
+		var posted []string
+
+		actual, read := patternWalk(t, &Options{
+			Include: []string{"**/f*", "d3/skip"},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				posted = append(posted, filepath.FromSlash(osPathname))
+				return nil
+			},
+		})
+
+		expected := []string{
+			osDirname,
+			filepath.Join(osDirname, "d2/f3"),
+			filepath.Join(osDirname, "d3/f4"),
+			filepath.Join(osDirname, "d3/skip"),
+			filepath.Join(osDirname, "d3/skip/f5"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, posted, []string{osDirname, filepath.Join(osDirname, "d3/skip")})
+		ensureStringSlicesMatch(t, read, []string{
+			osDirname,
+			filepath.Join(osDirname, "d2"),
+			filepath.Join(osDirname, "d3"),
+			filepath.Join(osDirname, "d3/skip"),
+		})
+	})
+
+	t.Run("exclude takes precedence", func(t *testing.T) {
+		actual, _ := patternWalk(t, &Options{
+			Include:  []string{"**/f*"},
+			Exclude:  []string{"d3/skip"},
+			MinDepth: 1,
+		})
+
+		expected := []string{
+			filepath.Join(osDirname, "d2/f3"),
+			filepath.Join(osDirname, "d3/f4"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("walker", func(t *testing.T) {
+		w, err := NewWalker(osDirname, &Options{Include: []string{"**/z?"}, Exclude: []string{"d2"}})
+		ensureError(t, err)
+		var actual []string
+		for w.Next() {
+			actual = append(actual, filepath.FromSlash(w.Path()))
+		}
+		ensureError(t, w.Close())
+
+		ensureStringSlicesMatch(t, actual, []string{osDirname, filepath.Join(osDirname, "d3/z2")})
+	})
+
+	t.Run("malformed pattern", func(t *testing.T) {
+		err := Walk(osDirname, &Options{
+			Callback: func(_ string, _ *Dirent) error {
+				t.Error("GOT: callback; WANT: no callback")
+				return nil
+			},
+			Exclude: []string{"[z"},
+		})
+		ensureError(t, err, "cannot compile pattern")
+	})
+}
+
+func TestWalkSkipHidden(t *testing.T) {
+	testroot, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+	ensureError(t, err)
+	defer os.RemoveAll(testroot)
+
+	// The root itself is hidden, but is still walked.
+	osDirname := filepath.Join(testroot, ".root")
+	for _, name := range []string{".dotfile", ".hidden/x", "a", "visible/.cache/y", "visible/z"} {
+		osPathname := filepath.Join(osDirname, filepath.FromSlash(name))
+		ensureError(t, os.MkdirAll(filepath.Dir(osPathname), os.ModePerm))
+		ensureError(t, ioutil.WriteFile(osPathname, nil, 0644))
+	}
+
+	expected := []string{
+		osDirname,
+		filepath.Join(osDirname, "a"),
+		filepath.Join(osDirname, "visible"),
+		filepath.Join(osDirname, "visible/z"),
+	}
+
+	for _, options := range []Options{
+		{},
+		{Unsorted: true},
+		{Order: BreadthFirst},
+		{Order: DirectoriesFirst, Unsorted: true},
+	} {
+		var actual []string
+		options.SkipHidden = true
+		options.Callback = func(osPathname string, _ *Dirent) error {
+			actual = append(actual, osPathname)
+			return nil
+		}
+		ensureError(t, Walk(osDirname, &options))
+		ensureStringSlicesMatch(t, actual, expected)
+	}
+
+	t.Run("walker", func(t *testing.T) {
+		w, err := NewWalker(osDirname, &Options{SkipHidden: true})
+		ensureError(t, err)
+		var actual []string
+		for w.Next() {
+			actual = append(actual, w.Path())
+		}
+		ensureError(t, w.Close())
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+}
+
+func TestWalkFilter(t *testing.T) {
+	osDirname := filepath.Join(scaffolingRoot, "d0/skips")
+
+	// notSkip rejects the nodes named skip, but Walk still descends into them.
+	notSkip := func(_ string, de *Dirent) (bool, error) {
+		return de.Name() != "skip", nil
+	}
+
+	t.Run("filter", func(t *testing.T) {
+		var actual, posted []string
+		err := Walk(osDirname, &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			PostChildrenCallback: func(osPathname string, _ *Dirent) error {
+				posted = append(posted, filepath.FromSlash(osPathname))
+				return nil
+			},
+			Filter: notSkip,
+		})
+		ensureError(t, err)
+
+		expected := []string{
+			osDirname,
+			filepath.Join(osDirname, "d2"),
+			filepath.Join(osDirname, "d2/f3"),
+			filepath.Join(osDirname, "d2/z1"),
+			filepath.Join(osDirname, "d3"),
+			filepath.Join(osDirname, "d3/f4"),
+			filepath.Join(osDirname, "d3/skip/f5"),
+			filepath.Join(osDirname, "d3/z2"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, posted, []string{osDirname, filepath.Join(osDirname, "d2"), filepath.Join(osDirname, "d3")})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		var actual, failed []string
+		err := Walk(osDirname, &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, filepath.FromSlash(osPathname))
+				return nil
+			},
+			ErrorCallback: func(osPathname string, err error) ErrorAction {
+				failed = append(failed, filepath.FromSlash(osPathname))
+				return SkipNode
+			},
+			Filter: func(osPathname string, de *Dirent) (bool, error) {
+				if de.Name() == "d3" {
+					return false, errors.New("filter failed")
+				}
+				return true, nil
+			},
+		})
+		ensureError(t, err)
+
+		expected := []string{
+			osDirname,
+			filepath.Join(osDirname, "d2"),
+			filepath.Join(osDirname, "d2/f3"),
+			filepath.Join(osDirname, "d2/skip"),
+			filepath.Join(osDirname, "d2/z1"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+		ensureStringSlicesMatch(t, failed, []string{filepath.Join(osDirname, "d3")})
+	})
+
+	t.Run("walker", func(t *testing.T) {
+		w, err := NewWalker(osDirname, &Options{Filter: notSkip, MinDepth: 2})
+		ensureError(t, err)
+		var actual []string
+		for w.Next() {
+			actual = append(actual, filepath.FromSlash(w.Path()))
+		}
+		ensureError(t, w.Close())
+
+		expected := []string{
+			filepath.Join(osDirname, "d2/f3"),
+			filepath.Join(osDirname, "d2/z1"),
+			filepath.Join(osDirname, "d3/f4"),
+			filepath.Join(osDirname, "d3/skip/f5"),
+			filepath.Join(osDirname, "d3/z2"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+}
@@ -0,0 +1,121 @@
+//go:build go1.16
+// +build go1.16
+
+package godirwalk
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+// errWalkDirHalt is returned by the Callback function WalkDir provides to walk
+// when the fs.WalkDirFunc returns an error that halts the walk.
+var errWalkDirHalt = errors.New("walk halted by fs.WalkDirFunc")
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory in the tree, including root, exactly like filepath.WalkDir, but
+// using the faster directory reading of this library. It is intended as a
+// drop-in replacement for filepath.WalkDir:
+//
+//    err := godirwalk.WalkDir(root, func(pathname string, d fs.DirEntry, err error) error {
+//        if err != nil {
+//            return err
+//        }
+//        fmt.Println(pathname)
+//        return nil
+//    }, nil)
+//
+// The provided function follows the fs.WalkDirFunc contract. It is invoked with
+// a non-nil error when root cannot be queried, in which case the fs.DirEntry is
+// nil, and a second time for a directory that cannot be read, in which case its
+// return value determines whether the walk skips that directory or halts.
+// Returning fs.SkipDir for a directory skips its contents, returning fs.SkipDir
+// for any other node skips the remaining nodes in the directory containing it,
+// and returning SkipAll, which is fs.SkipAll on Go 1.20 and later, stops the
+// walk. WalkDir returns nil in these cases, and otherwise the first non-nil
+// error returned by fn. The fs.DirEntry provided to fn is a *Dirent.
+//
+// The options may be nil. When provided, WalkDir honors every option except the
+// Callback, ErrorCallback, ChildrenCallback, PostChildrenCallback,
+// DanglingSymlinkCallback, AllowNonDirectory, and Workers options, which it
+// ignores, because those are determined by the WalkDir contract.
+func WalkDir(root string, fn fs.WalkDirFunc, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+
+	cleanRoot := filepath.Clean(root)
+
+	var visited bool   // whether root was found
+	var halt error     // error returned by fn that halts the walk
+	var last *Dirent   // node most recently provided to fn
+	var skipped string // directory whose remaining nodes are skipped
+
+	o.AllowNonDirectory = true
+	o.Workers = 0
+	o.ChildrenCallback = nil
+	o.PostChildrenCallback = nil
+	o.DanglingSymlinkCallback = nil
+
+	o.Callback = func(osPathname string, de *Dirent) error {
+		visited = true
+		if de.path == skipped {
+			return SkipThis
+		}
+		skipped = ""
+		if osPathname == cleanRoot {
+			osPathname = root // provide root exactly as given, like filepath.WalkDir
+		}
+		last = de
+		err := fn(osPathname, de, nil)
+		if err == filepath.SkipDir && !de.IsDir() {
+			// Like filepath.WalkDir, skip the remaining nodes in the directory
+			// containing a node that is not a directory, even a symbolic link
+			// to a directory, which Walk would merely not descend into.
+			skipped = de.path
+			return SkipThis
+		}
+		if err == nil || err == filepath.SkipDir || err == SkipAll {
+			return err
+		}
+		halt = err
+		return errWalkDirHalt
+	}
+
+	o.ErrorCallback = func(osPathname string, err error) ErrorAction {
+		visited = true
+		if err == errWalkDirHalt {
+			return Halt
+		}
+		if osPathname == cleanRoot {
+			osPathname = root
+		}
+		var d fs.DirEntry
+		if last != nil && filepath.Join(last.path, last.name) == filepath.Clean(osPathname) {
+			d = last
+		} else if de, err := NewDirent(osPathname); err == nil {
+			d = de
+		}
+		err = fn(osPathname, d, err)
+		if err == nil || err == filepath.SkipDir {
+			return SkipNode
+		}
+		halt = err
+		return Halt
+	}
+
+	err := walkContext(context.Background(), cleanRoot, &o, nil)
+	if err != nil && !visited {
+		// Walk could not even query root.
+		err = fn(root, nil, err)
+	} else if halt != nil {
+		err = halt
+	}
+	if err == filepath.SkipDir || err == SkipAll {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,22 @@
+//go:build go1.20
+// +build go1.20
+
+package godirwalk
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalkDirSkipAll(t *testing.T) {
+	ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0"), func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == "d2" {
+			return fs.SkipAll
+		}
+		return nil
+	})
+}
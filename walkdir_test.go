@@ -0,0 +1,167 @@
+//go:build go1.16
+// +build go1.16
+
+package godirwalk
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// walkDirRecorder returns a fs.WalkDirFunc that records each of its
+// invocations, and returns the value action returns for it.
+func walkDirRecorder(events *[]string, action func(string, fs.DirEntry, error) error) fs.WalkDirFunc {
+	return func(osPathname string, d fs.DirEntry, err error) error {
+		event := osPathname
+		if d != nil {
+			event += fmt.Sprintf(" %s %v %v", d.Name(), d.IsDir(), d.Type())
+		}
+		if err != nil {
+			event += " error"
+		}
+		*events = append(*events, event)
+		return action(osPathname, d, err)
+	}
+}
+
+// Ensure the invocations of the fs.WalkDirFunc and the error returned by this
+// library's WalkDir function exactly match those of filepath.WalkDir.
+func ensureSameAsWalkDir(tb testing.TB, root string, action func(string, fs.DirEntry, error) error) {
+	tb.Helper()
+
+	var actual, expected []string
+
+	actualErr := WalkDir(root, walkDirRecorder(&actual, action), nil)
+	expectedErr := filepath.WalkDir(root, walkDirRecorder(&expected, action))
+
+	if got, want := fmt.Sprint(actualErr), fmt.Sprint(expectedErr); got != want {
+		tb.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+
+	for i := 0; i < len(actual) || i < len(expected); i++ {
+		switch {
+		case i >= len(expected):
+			tb.Errorf("GOT: %q (extra)", actual[i])
+		case i >= len(actual):
+			tb.Errorf("WANT: %q (missing)", expected[i])
+		case actual[i] != expected[i]:
+			tb.Errorf("GOT: %q; WANT: %q", actual[i], expected[i])
+		}
+	}
+}
+
+// Test that WalkDir provides exactly the filepath.WalkDir contract, including
+// the order of invocations, the handling of fs.SkipDir, and the error-first
+// invocations of the fs.WalkDirFunc.
+func TestWalkDirCompatibleWithFilepathWalkDir(t *testing.T) {
+	skip := func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Name() == "skip" {
+			return fs.SkipDir
+		}
+		return nil
+	}
+
+	t.Run("test root", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0"), skip)
+	})
+
+	t.Run("unclean root", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0")+"/./skips/", skip)
+	})
+
+	t.Run("skip file at root", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0/skips/d2"), skip)
+	})
+
+	t.Run("skip dir at root", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0/skips/d3"), skip)
+	})
+
+	t.Run("skip root", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0/skips/d3/skip"), skip)
+	})
+
+	t.Run("root is file", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0/f1"), skip)
+	})
+
+	t.Run("root is symlink", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0/symlinks/toD1"), skip)
+	})
+
+	t.Run("skip symlink to directory", func(t *testing.T) {
+		osDirname, err := ioutil.TempDir(os.TempDir(), "godirwalk-")
+		ensureError(t, err)
+		defer os.RemoveAll(osDirname)
+
+		ensureError(t, os.MkdirAll(filepath.Join(osDirname, "real"), os.ModePerm))
+		ensureError(t, ioutil.WriteFile(filepath.Join(osDirname, "real", "f"), nil, 0644))
+		ensureError(t, ioutil.WriteFile(filepath.Join(osDirname, "b_file"), nil, 0644))
+		if err := os.Symlink("real", filepath.Join(osDirname, "a_link")); err != nil {
+			t.Skipf("cannot create symbolic link: %s", err)
+		}
+
+		ensureSameAsWalkDir(t, osDirname, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Name() == "a_link" {
+				return fs.SkipDir
+			}
+			return nil
+		})
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "missing"), skip)
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "missing"), func(_ string, _ fs.DirEntry, _ error) error {
+			return nil
+		})
+	})
+
+	t.Run("halt", func(t *testing.T) {
+		ensureSameAsWalkDir(t, filepath.Join(scaffolingRoot, "d0"), func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Name() == "d2" {
+				return fmt.Errorf("halt at %s", d.Name())
+			}
+			return nil
+		})
+	})
+
+	t.Run("unreadable directory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("cannot create an unreadable directory for the super user")
+		}
+
+		osDirname := filepath.Join(scaffolingRoot, "unreadable")
+		ensureError(t, os.MkdirAll(filepath.Join(osDirname, "d5"), os.ModePerm))
+		ensureError(t, os.Chmod(filepath.Join(osDirname, "d5"), 0))
+		defer func() {
+			ensureError(t, os.Chmod(filepath.Join(osDirname, "d5"), os.ModePerm))
+			ensureError(t, os.RemoveAll(osDirname))
+		}()
+
+		ensureSameAsWalkDir(t, osDirname, func(_ string, _ fs.DirEntry, err error) error {
+			return err
+		})
+		ensureSameAsWalkDir(t, osDirname, func(_ string, _ fs.DirEntry, _ error) error {
+			return nil
+		})
+		ensureSameAsWalkDir(t, osDirname, func(_ string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return fs.SkipDir
+			}
+			return nil
+		})
+	})
+}
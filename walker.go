@@ -0,0 +1,290 @@
+package godirwalk
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// Walker is an iterator that walks a file system hierarchy in the same order as
+// Walk, but lets the caller pull each file system node from a loop rather than
+// having Walk push each node to a callback function. Rather than recursing, a
+// Walker keeps an explicit stack of directories it has open, so the caller may
+// pause between calls to Next for as long as it likes.
+//
+//    w, err := godirwalk.NewWalker(dirname, nil)
+//    if err != nil {
+//        fatal("cannot walk directory: %s", err)
+//    }
+//    defer w.Close()
+//
+//    for w.Next() {
+//        if w.Dirent().Name() == ".git" {
+//            w.SkipDir()
+//            continue
+//        }
+//        fmt.Printf("%s %s\n", w.Dirent().ModeType(), w.Path())
+//    }
+//    if err := w.Err(); err != nil {
+//        fatal("cannot walk directory: %s", err)
+//    }
+//
+// A Walker honors the same Options as Walk, including Order, except for
+// Callback, ChildrenCallback, PostChildrenCallback, and Workers, which are
+// ignored. When an ErrorCallback function is provided, it determines whether
+// the Walker skips the node that caused an error or halts. Otherwise any error
+// halts the Walker, and is returned by its Err method.
+type Walker struct {
+	ws            *walkState
+	scratchBuffer []byte
+	stack         []walkerFrame // directories being enumerated, innermost last
+	queue         []pendingDir  // directories yet to be read when breadth first
+	osPathname    string        // pathname of the current node
+	de            *Dirent       // current node, or nil when there is none
+	depth         int           // depth of the current node below the root
+	returned      bool          // whether the current node was returned by Next
+	skip          bool          // whether SkipDir was invoked on the current node
+	err           error         // error that halted the walk
+}
+
+// walkerFrame is a directory being enumerated by a Walker.
+type walkerFrame struct {
+	osDirname string
+	ds        scanner
+	depth     int       // depth of the children of the directory below the root
+	ancestor  *ancestor // ancestor the children of the directory are walked with
+}
+
+// NewWalker returns a new Walker that walks the file tree rooted at the
+// specified directory. The options may be nil, in which case the Walker uses
+// the same defaults as Walk. To prevent resource leaks, the caller must invoke
+// the Walker's Close method unless Next has returned false.
+func NewWalker(pathname string, options *Options) (*Walker, error) {
+	var o Options
+	if options != nil {
+		o = *options
+	}
+
+	pathname = filepath.Clean(pathname)
+	o.ChildrenCallback = nil // a Walker is driven by its caller instead
+
+	ws := &walkState{options: &o, ctx: context.Background()}
+
+	dirent, err := ws.rootDirent(pathname)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.ScratchBuffer) < MinimumScratchBufferSize {
+		o.ScratchBuffer = newScratchBuffer()
+	}
+	if o.ErrorCallback == nil {
+		o.ErrorCallback = defaultErrorCallback
+	}
+
+	return &Walker{
+		ws:            ws,
+		scratchBuffer: o.ScratchBuffer,
+		osPathname:    pathname,
+		de:            dirent,
+	}, nil
+}
+
+// Close releases the resources associated with the Walker, closing every
+// directory it has open. It is safe to call Close before Next has returned
+// false. Close returns any error encountered while closing those directories,
+// or otherwise the same error as Err.
+func (w *Walker) Close() error {
+	var err error
+	for len(w.stack) > 0 {
+		if err2 := w.pop(); err == nil {
+			err = err2
+		}
+	}
+	w.de, w.queue = nil, nil
+	w.ws.close()
+	if err == nil {
+		err = w.err
+	}
+	return err
+}
+
+// Dirent returns the directory entry for the file system node most recently
+// returned by Next.
+func (w *Walker) Dirent() *Dirent {
+	if !w.returned {
+		return nil
+	}
+	return w.de
+}
+
+// Err returns the error, if any, that halted the Walker. It returns nil when
+// the Walker finished visiting every node.
+func (w *Walker) Err() error { return w.err }
+
+// Path returns the OS pathname of the file system node most recently returned
+// by Next. Like the pathname provided to a WalkFunc, it contains the argument
+// provided to NewWalker as a prefix.
+func (w *Walker) Path() string {
+	if !w.returned {
+		return ""
+	}
+	return w.osPathname
+}
+
+// SkipDir causes the Walker to skip the file system node most recently returned
+// by Next, exactly as if a Callback function returned filepath.SkipDir for that
+// node: when the node is a directory, or a symbolic link to a directory, the
+// Walker will not descend into it; otherwise the Walker will skip the remaining
+// nodes in the directory containing it.
+func (w *Walker) SkipDir() {
+	if w.returned {
+		w.skip = true
+	}
+}
+
+// Next advances the Walker to the next file system node, which is then
+// available through the Path and Dirent methods. It returns false when there
+// are no more nodes to visit, or when an error halts the Walker, after
+// releasing all resources associated with the Walker.
+func (w *Walker) Next() bool {
+	options := w.ws.options
+
+	for w.err == nil {
+		if w.de != nil {
+			if !w.returned {
+				invoke, err := w.ws.invokes(w.osPathname, w.de, w.depth)
+				if err != nil {
+					if action := options.ErrorCallback(w.osPathname, err); action != SkipNode {
+						w.err = err
+						break
+					}
+					w.de = nil // neither return nor descend into it
+					continue
+				}
+				if invoke {
+					w.returned = true
+					return true
+				}
+			}
+			// Before moving on, descend into the current node, whether or not
+			// it was returned.
+			err := w.descend()
+			w.de, w.returned, w.skip = nil, false, false
+			if err != nil {
+				w.err = err
+				break
+			}
+		}
+
+		if len(w.stack) == 0 {
+			if len(w.queue) == 0 {
+				return false // visited every node
+			}
+			if err := w.dequeue(); err != nil {
+				w.err = err
+				break
+			}
+			continue
+		}
+
+		top := &w.stack[len(w.stack)-1]
+		if !top.ds.Scan() {
+			osDirname := top.osDirname
+			if err := w.pop(); err != nil {
+				if action := options.ErrorCallback(osDirname, err); action != SkipNode {
+					w.err = err
+				}
+			}
+			continue
+		}
+
+		de, err := top.ds.Dirent()
+		osChildname := w.ws.join(top.osDirname, de.name)
+		if err != nil {
+			if action := options.ErrorCallback(osChildname, err); action != SkipNode {
+				w.err = err
+			}
+			continue
+		}
+		if w.ws.excluded(osChildname, de, top.depth, top.ancestor) {
+			continue // neither return nor descend into it
+		}
+		w.osPathname, w.de, w.depth = osChildname, de, top.depth
+	}
+
+	_ = w.Close()
+	return false
+}
+
+// descend pushes a frame to enumerate the children of the current node when the
+// Walker ought to descend into it. When SkipDir was invoked on a node other
+// than a directory, it instead pops the frame of the directory containing that
+// node.
+func (w *Walker) descend() error {
+	options := w.ws.options
+
+	dirfd, parent := atFDCWD, (*ancestor)(nil)
+	if len(w.stack) > 0 {
+		top := &w.stack[len(w.stack)-1]
+		dirfd, parent = top.ds.dirfd(), top.ancestor
+	}
+
+	if w.skip {
+		isDir, err := w.ws.isDirOrSymlinkToDir(dirfd, w.osPathname, w.de)
+		if err != nil {
+			if action := options.ErrorCallback(w.osPathname, err); action == SkipNode {
+				return nil
+			}
+			return err
+		}
+		if !isDir && len(w.stack) > 0 {
+			return w.pop() // stop processing remaining siblings
+		}
+		return nil
+	}
+
+	if options.Order == BreadthFirst {
+		// Read the directory once every node before it in the queue was read.
+		if w.de.IsDir() || w.de.IsSymlink() {
+			w.queue = append(w.queue, pendingDir{osPathname: w.osPathname, dirent: w.de, depth: w.depth, parent: parent})
+		}
+		return nil
+	}
+
+	return w.push(pendingDir{osPathname: w.osPathname, dirent: w.de, depth: w.depth, parent: parent}, dirfd)
+}
+
+// dequeue pushes a frame to enumerate the children of the directory at the
+// front of the queue, when the Walker ought to descend into it.
+func (w *Walker) dequeue() error {
+	pd := w.queue[0]
+	w.queue[0] = pendingDir{} // release for garbage collection
+	w.queue = w.queue[1:]
+	return w.push(pd, atFDCWD)
+}
+
+// push pushes a frame to enumerate the children of the directory pd, found in
+// the directory open as dirfd, when the Walker ought to descend into it.
+func (w *Walker) push(pd pendingDir, dirfd int) error {
+	ds, self, err := w.ws.readDirectory(pd.osPathname, pd.dirent, pd.depth, dirfd, pd.parent, w.scratchBuffer)
+	if err != nil {
+		if action := w.ws.errorAction(pd.osPathname, err); action == SkipNode {
+			return nil
+		}
+		return err
+	}
+	if ds != nil {
+		w.stack = append(w.stack, walkerFrame{osDirname: pd.osPathname, ds: ds, depth: pd.depth + 1, ancestor: self})
+	}
+	return nil
+}
+
+// pop removes the innermost frame from the stack, releasing the resources used
+// to enumerate its directory.
+func (w *Walker) pop() error {
+	i := len(w.stack) - 1
+	err := w.stack[i].ds.Err()
+	w.stack[i] = walkerFrame{}
+	w.stack = w.stack[:i]
+	return err
+}
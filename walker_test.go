@@ -0,0 +1,129 @@
+package godirwalk
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func walkerWalk(tb testing.TB, osDirname string, options *Options) []string {
+	tb.Helper()
+	w, err := NewWalker(osDirname, options)
+	ensureError(tb, err)
+	var entries []string
+	for w.Next() {
+		if w.Dirent().Name() == "skip" {
+			w.SkipDir()
+			continue
+		}
+		entries = append(entries, filepath.FromSlash(w.Path()))
+	}
+	ensureError(tb, w.Err())
+	ensureError(tb, w.Close())
+	return entries
+}
+
+func TestWalker(t *testing.T) {
+	t.Run("same order as walk", func(t *testing.T) {
+		osDirname := filepath.Join(scaffolingRoot, "d0")
+		actual := walkerWalk(t, osDirname, nil)
+		expected := godirwalkWalk(t, osDirname)
+
+		if got, want := len(actual), len(expected); got != want {
+			t.Fatalf("GOT: %v; WANT: %v", got, want)
+		}
+		for i := range actual {
+			if got, want := actual[i], expected[i]; got != want {
+				t.Errorf("GOT: %v; WANT: %v", got, want)
+			}
+		}
+	})
+
+	t.Run("skip dir", func(t *testing.T) {
+		osDirname := filepath.Join(scaffolingRoot, "d0/skips")
+		actual := walkerWalk(t, osDirname, &Options{Unsorted: true})
+		expected := godirwalkWalkUnsorted(t, osDirname)
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("follow symbolic links", func(t *testing.T) {
+		var errorCallbackVisited bool
+
+		actual := walkerWalk(t, filepath.Join(scaffolingRoot, "d0/symlinks"), &Options{
+			ErrorCallback: func(osPathname string, err error) ErrorAction {
+				if filepath.Base(osPathname) == "nothing" {
+					errorCallbackVisited = true
+					return SkipNode
+				}
+				return Halt
+			},
+			FollowSymbolicLinks: true,
+		})
+
+		if got, want := errorCallbackVisited, true; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/symlinks"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSD1/f2"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/d4/toSF1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/nothing"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toAbs"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toD1"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toD1/f2"),
+			filepath.Join(scaffolingRoot, "d0/symlinks/toF1"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("halt", func(t *testing.T) {
+		w, err := NewWalker(filepath.Join(scaffolingRoot, "d0/symlinks"), &Options{FollowSymbolicLinks: true})
+		ensureError(t, err)
+
+		for w.Next() {
+		}
+
+		ensureError(t, w.Err(), "nothing")
+		ensureError(t, w.Close(), "nothing")
+	})
+
+	t.Run("min and max depth", func(t *testing.T) {
+		actual := walkerWalk(t, filepath.Join(scaffolingRoot, "d0/skips"), &Options{
+			MaxDepth: 2,
+			MinDepth: 2,
+		})
+
+		expected := []string{
+			filepath.Join(scaffolingRoot, "d0/skips/d2/f3"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/f4"),
+			filepath.Join(scaffolingRoot, "d0/skips/d3/z2"),
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("close early", func(t *testing.T) {
+		w, err := NewWalker(filepath.Join(scaffolingRoot, "d0"), &Options{Unsorted: true})
+		ensureError(t, err)
+
+		for w.Next() {
+			if w.Dirent().Name() == "f2" {
+				break
+			}
+		}
+
+		if got, want := len(w.stack), 2; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		ensureError(t, w.Close())
+		if got, want := len(w.stack), 0; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+		if got, want := w.Next(), false; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+//go:build go1.16
+// +build go1.16
+
+package godirwalk
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// Dirent satisfies the fs.DirEntry interface.
+var _ fs.DirEntry = (*Dirent)(nil)
+
+// WalkFS walks the file tree rooted at the specified directory of the provided
+// file system, exactly like Walk walks the file tree of the operating system,
+// so the same traversal code can be used for the real disk, an embed.FS, a
+// zip.Reader, or an fstest.MapFS in tests.
+//
+// Like with io/fs, the root and the pathnames provided to the callback
+// functions are slash-separated, and the root must satisfy fs.ValidPath after
+// it has been cleaned. Directories are read with fs.ReadDir, which uses the
+// ReadDir method of the file system when it implements fs.ReadDirFS, and the
+// mode type of each Dirent is obtained from the Type method of the
+// corresponding fs.DirEntry, without requiring a Stat of the node. Because
+// directories are always read entirely, the Unsorted and ScratchBuffer options
+// are ignored. When the symbolic link policy follows symbolic links, they are
+// resolved with fs.Stat, and the Info method of each Dirent returns the
+// information provided by the corresponding fs.DirEntry.
+//
+//    err := godirwalk.WalkFS(os.DirFS(dirname), ".", &godirwalk.Options{
+//        Callback: func(pathname string, de *godirwalk.Dirent) error {
+//            fmt.Printf("%s %s\n", de.ModeType(), pathname)
+//            return nil
+//        },
+//    })
+func WalkFS(fsys fs.FS, root string, options *Options) error {
+	return walkContext(context.Background(), path.Clean(root), options, fsReader{fsys})
+}
+
+// fsReader is a dirReader that reads from an io/fs.FS.
+type fsReader struct {
+	fsys fs.FS
+}
+
+func (r fsReader) readDirents(osDirname string) (Dirents, error) {
+	entries, err := fs.ReadDir(r.fsys, osDirname)
+	if err != nil {
+		return nil, err
+	}
+	deChildren := make(Dirents, len(entries))
+	for i, entry := range entries {
+		deChildren[i] = &Dirent{
+			name:     entry.Name(),
+			path:     osDirname,
+			modeType: entry.Type(),
+			reader:   fsEntry{fsReader: r, entry: entry},
+		}
+	}
+	return deChildren, nil
+}
+
+func (r fsReader) stat(osPathname string) (os.FileInfo, error) {
+	return fs.Stat(r.fsys, osPathname)
+}
+
+// readFile returns the contents of the specified ignore file, subject to the
+// same checks as readFileAt, except that io/fs provides no means to open a node
+// without following symbolic links or blocking.
+func (r fsReader) readFile(osPathname string) ([]byte, error) {
+	fh, err := r.fsys.Open(osPathname)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readIgnoreFile(fh, osPathname)
+	_ = fh.Close()
+	return data, err
+}
+
+// lstat returns the file information of the specified node. Because io/fs
+// provides no means to query a node without following symbolic links, this
+// is only used for the root of a walk, which is resolved with fs.Stat anyway.
+// The Dirents of the children of a directory use fsEntry instead.
+func (r fsReader) lstat(osPathname string) (os.FileInfo, error) {
+	return fs.Stat(r.fsys, osPathname)
+}
+
+// fsEntry is the dirReader of a Dirent read from a directory of an io/fs.FS,
+// which obtains the file information of that Dirent from its fs.DirEntry.
+type fsEntry struct {
+	fsReader
+	entry fs.DirEntry
+}
+
+func (e fsEntry) lstat(_ string) (os.FileInfo, error) {
+	return e.entry.Info()
+}
@@ -0,0 +1,223 @@
+//go:build go1.16
+// +build go1.16
+
+package godirwalk
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWalkFS(t *testing.T) {
+	t.Run("map fs", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"d0/f1":          {},
+			"d0/d1/f2":       {},
+			"d0/skips/d2/f3": {},
+			"d0/skips/d3/f4": {},
+		}
+
+		var actual, posted []string
+
+		err := WalkFS(fsys, "d0/", &Options{
+			Callback: func(pathname string, de *Dirent) error {
+				if de.Name() == "d3" {
+					return SkipThis
+				}
+				actual = append(actual, pathname)
+				return nil
+			},
+			PostChildrenCallback: func(pathname string, _ *Dirent) error {
+				posted = append(posted, pathname)
+				return nil
+			},
+		})
+
+		ensureError(t, err)
+
+		expected := []string{"d0", "d0/d1", "d0/d1/f2", "d0/f1", "d0/skips", "d0/skips/d2", "d0/skips/d2/f3"}
+		if got, want := len(actual), len(expected); got != want {
+			t.Fatalf("GOT: %v; WANT: %v", actual, expected)
+		}
+		for i := range actual {
+			if got, want := actual[i], expected[i]; got != want {
+				t.Errorf("GOT: %v; WANT: %v", got, want)
+			}
+		}
+
+		ensureStringSlicesMatch(t, posted, []string{"d0", "d0/d1", "d0/skips", "d0/skips/d2"})
+	})
+
+	t.Run("dir fs", func(t *testing.T) {
+		osDirname := filepath.Join(scaffolingRoot, "d0")
+		var expected []string
+		err := Walk(osDirname, &Options{
+			Callback: func(osPathname string, de *Dirent) error {
+				rel, err := filepath.Rel(scaffolingRoot, osPathname)
+				if err != nil {
+					return err
+				}
+				expected = append(expected, filepath.ToSlash(rel)+" "+de.ModeType().String())
+				return nil
+			},
+			ErrorCallback: func(_ string, _ error) ErrorAction {
+				return SkipNode // ignore the dangling symbolic link
+			},
+			FollowSymbolicLinks: true,
+		})
+		ensureError(t, err)
+
+		var actual []string
+		err = WalkFS(os.DirFS(scaffolingRoot), "d0", &Options{
+			Callback: func(pathname string, de *Dirent) error {
+				actual = append(actual, pathname+" "+de.ModeType().String())
+				return nil
+			},
+			ErrorCallback: func(_ string, _ error) ErrorAction {
+				return SkipNode // ignore the dangling symbolic link
+			},
+			FollowSymbolicLinks: true,
+		})
+		ensureError(t, err)
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("invalid root", func(t *testing.T) {
+		err := WalkFS(fstest.MapFS{}, "../d0", &Options{
+			Callback: func(_ string, _ *Dirent) error { return nil },
+		})
+		ensureError(t, err, "../d0")
+	})
+
+	t.Run("git ignore", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"repo/.git/HEAD":      {},
+			"repo/.gitignore":     {Data: []byte("*.o\n")},
+			"repo/main.c":         {},
+			"repo/main.o":         {},
+			"repo/sub/.gitignore": {Data: []byte("!keep.o\n")},
+			"repo/sub/keep.o":     {},
+			"repo/sub/drop.o":     {},
+		}
+
+		var actual []string
+
+		err := WalkFS(fsys, "repo", &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, osPathname)
+				return nil
+			},
+			GitIgnore: true,
+		})
+		ensureError(t, err)
+
+		expected := []string{
+			"repo",
+			"repo/.gitignore",
+			"repo/main.c",
+			"repo/sub",
+			"repo/sub/.gitignore",
+			"repo/sub/keep.o",
+		}
+
+		ensureStringSlicesMatch(t, actual, expected)
+	})
+
+	t.Run("skip hidden", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			".top/.cache/x": {},
+			".top/.rc":      {},
+			".top/a/.b":     {},
+			".top/a/c":      {},
+		}
+
+		var actual []string
+
+		err := WalkFS(fsys, ".top", &Options{
+			Callback: func(osPathname string, _ *Dirent) error {
+				actual = append(actual, osPathname)
+				return nil
+			},
+			SkipHidden: true,
+		})
+		ensureError(t, err)
+
+		ensureStringSlicesMatch(t, actual, []string{".top", ".top/a", ".top/a/c"})
+	})
+
+	t.Run("workers", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"d0/f": {},
+			"d1/f": {},
+			"d2/f": {},
+			"d3/f": {},
+		}
+
+		var mu sync.Mutex
+		var inside, most int
+
+		err := WalkFS(fsys, ".", &Options{
+			Callback: func(_ string, de *Dirent) error {
+				if de.IsDir() {
+					return nil
+				}
+				mu.Lock()
+				if inside++; inside > most {
+					most = inside
+				}
+				mu.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				mu.Lock()
+				inside--
+				mu.Unlock()
+				return nil
+			},
+			Workers: 4,
+		})
+		ensureError(t, err)
+
+		if most < 2 {
+			t.Errorf("GOT: %v; WANT: at least 2 files visited concurrently", most)
+		}
+	})
+}
+
+func TestDirentDirEntry(t *testing.T) {
+	fsys := fstest.MapFS{
+		"d0/f1": {Data: []byte("hello\n")},
+	}
+
+	var entries []fs.DirEntry
+
+	err := WalkFS(fsys, "d0", &Options{
+		Callback: func(_ string, de *Dirent) error {
+			entries = append(entries, de)
+			return nil
+		},
+	})
+	ensureError(t, err)
+
+	if got, want := len(entries), 2; got != want {
+		t.Fatalf("GOT: %v; WANT: %v", got, want)
+	}
+
+	if got, want := entries[0].IsDir(), true; got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+	if got, want := entries[1].Type(), fs.FileMode(0); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+
+	fi, err := entries[1].Info()
+	ensureError(t, err)
+
+	if got, want := fi.Size(), int64(6); got != want {
+		t.Errorf("GOT: %v; WANT: %v", got, want)
+	}
+}